@@ -0,0 +1,383 @@
+package jsonrpc
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CodeUnauthenticated is the error code sent when a request's credentials are
+// missing or invalid.
+const CodeUnauthenticated int = -32002
+
+// HMACScheme is the Authorization scheme of HMAC signed requests.
+const HMACScheme = "HMAC-SHA256"
+
+// DefaultHMACMaxSkew is how far the timestamp of a HMAC signed request may be
+// from the server's clock when HMACAuthenticator.MaxSkew is zero.
+const DefaultHMACMaxSkew = 5 * time.Minute
+
+var (
+	// ErrUnauthenticated is returned when a request carries no credentials
+	// accepted by any Authenticator.
+	ErrUnauthenticated = errors.New("jsonrpc: request is not authenticated")
+	// ErrInvalidCredentials is returned when a request's credentials are
+	// not accepted.
+	ErrInvalidCredentials = errors.New("jsonrpc: invalid credentials")
+)
+
+type principalContextKey struct{}
+
+// Principal identifies the caller of an authenticated request. Roles and
+// Scopes are checked against the Policy of methods registered with
+// RegisterWithPolicy.
+type Principal struct {
+	Name   string
+	Scheme string
+	Roles  []string
+	Scopes []string
+}
+
+// HasRole reports whether the principal has the role.
+func (principal *Principal) HasRole(role string) bool {
+	return contains(principal.Roles, role)
+}
+
+// HasScope reports whether the principal has the scope.
+func (principal *Principal) HasScope(scope string) bool {
+	return contains(principal.Scopes, scope)
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// PrincipalFromRequest returns the Principal of an authenticated request, or
+// nil if the request was not authenticated.
+func PrincipalFromRequest(req *http.Request) *Principal {
+	if req == nil {
+		return nil
+	}
+	return PrincipalFromContext(req.Context())
+}
+
+// PrincipalFromContext returns the Principal stored in the context, if any.
+func PrincipalFromContext(ctx context.Context) *Principal {
+	principal, _ := ctx.Value(principalContextKey{}).(*Principal)
+	return principal
+}
+
+// WithPrincipal returns a copy of the context holding the Principal.
+func WithPrincipal(ctx context.Context, principal *Principal) context.Context {
+	return context.WithValue(ctx, principalContextKey{}, principal)
+}
+
+// An Authenticator verifies the credentials of a request.
+//
+// Authenticate returns a nil Principal and a nil error when the request does
+// not carry credentials of the Authenticator's scheme, allowing another
+// Authenticator to be tried, and an error when it carries credentials that
+// are not valid.
+type Authenticator interface {
+	Authenticate(r *http.Request) (*Principal, error)
+}
+
+// BasicAuthenticator authenticates requests using HTTP basic auth, calling
+// the function to check the username and password.
+type BasicAuthenticator func(username string, password string) (*Principal, error)
+
+// Authenticate implements the Authenticator interface.
+func (fn BasicAuthenticator) Authenticate(r *http.Request) (*Principal, error) {
+	username, password, ok := r.BasicAuth()
+	if !ok {
+		return nil, nil
+	}
+	return fn(username, password)
+}
+
+// BearerAuthenticator authenticates requests carrying a bearer token in the
+// Authorization header, calling the function to check the token.
+type BearerAuthenticator func(token string) (*Principal, error)
+
+// Authenticate implements the Authenticator interface.
+func (fn BearerAuthenticator) Authenticate(r *http.Request) (*Principal, error) {
+	scheme, token := splitAuthorization(r)
+	if !strings.EqualFold(scheme, "Bearer") {
+		return nil, nil
+	}
+	return fn(token)
+}
+
+// HMACAuthenticator authenticates requests signed with a shared key by
+// HMACCredentials. The Authorization header has the form
+//
+//	HMAC-SHA256 <key id>:<unix timestamp>:<base64 signature>
+//
+// where the signature is the HMAC-SHA256 of the request method, path and
+// query, timestamp and hex encoded SHA-256 of the body, separated by newlines.
+//
+// Keys maps key IDs to keys, the key ID becoming the name of the Principal.
+// Requests with a timestamp more than MaxSkew (DefaultHMACMaxSkew when zero)
+// from the server's clock are rejected to limit replays. The body is read to
+// check its signature, so bodies larger than MaxBodySize bytes
+// (DefaultMaxBodySize when zero) are rejected by the AuthHandler with a 413
+// status.
+type HMACAuthenticator struct {
+	Keys        map[string][]byte
+	MaxSkew     time.Duration
+	MaxBodySize int64
+}
+
+// Authenticate implements the Authenticator interface.
+func (auth *HMACAuthenticator) Authenticate(r *http.Request) (*Principal, error) {
+	scheme, credentials := splitAuthorization(r)
+	if scheme != HMACScheme {
+		return nil, nil
+	}
+
+	parts := strings.SplitN(credentials, ":", 3)
+	if len(parts) != 3 {
+		return nil, ErrInvalidCredentials
+	}
+	keyID := parts[0]
+
+	key, ok := auth.Keys[keyID]
+	if !ok {
+		return nil, ErrInvalidCredentials
+	}
+
+	timestamp, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil {
+		return nil, ErrInvalidCredentials
+	}
+
+	maxSkew := auth.MaxSkew
+	if maxSkew == 0 {
+		maxSkew = DefaultHMACMaxSkew
+	}
+	skew := time.Since(time.Unix(timestamp, 0))
+	if skew > maxSkew || skew < -maxSkew {
+		return nil, ErrInvalidCredentials
+	}
+
+	signature, err := base64.StdEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, ErrInvalidCredentials
+	}
+
+	var body []byte
+	if r.Body != nil {
+		max := maxBodySize(auth.MaxBodySize)
+		body, err = ioutil.ReadAll(io.LimitReader(r.Body, max+1))
+		r.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		if int64(len(body)) > max {
+			return nil, errBodyTooLarge
+		}
+		// leave the body for the handler
+		r.Body = ioutil.NopCloser(bytes.NewReader(body))
+	}
+
+	expected := signRequest(key, r.Method, r.URL.RequestURI(), parts[1], body)
+	if !hmac.Equal(signature, expected) {
+		return nil, ErrInvalidCredentials
+	}
+
+	principal := &Principal{
+		Name:   keyID,
+		Scheme: HMACScheme,
+	}
+	return principal, nil
+}
+
+// signRequest returns the HMAC signature of a request. The uri is the path and
+// query of the request, as given by url.URL.RequestURI, so the method and
+// params of GET requests are signed.
+func signRequest(key []byte, method string, uri string, timestamp string, body []byte) []byte {
+	digest := sha256.Sum256(body)
+
+	mac := hmac.New(sha256.New, key)
+	fmt.Fprintf(mac, "%s\n%s\n%s\n%s", method, uri, timestamp, hex.EncodeToString(digest[:]))
+	return mac.Sum(nil)
+}
+
+// splitAuthorization returns the scheme and credentials of the request's
+// Authorization header.
+func splitAuthorization(r *http.Request) (string, string) {
+	authorization := strings.TrimSpace(r.Header.Get("Authorization"))
+	space := strings.IndexByte(authorization, ' ')
+	if space < 0 {
+		return authorization, ""
+	}
+	return authorization[:space], strings.TrimSpace(authorization[space+1:])
+}
+
+// SecureCompare reports whether two secrets are equal in constant time, for
+// use when checking passwords and tokens in BasicAuthenticator and
+// BearerAuthenticator functions.
+func SecureCompare(given string, actual string) bool {
+	return subtle.ConstantTimeCompare([]byte(given), []byte(actual)) == 1
+}
+
+// AuthHandler authenticates requests before passing them on to the wrapped
+// http.Handler, which can be a Handler, WebSocketHandler or any other.
+//
+// Each of the Authenticators is tried in turn and the first Principal
+// returned is stored in the request's context, where methods can get it
+// with PrincipalFromRequest. Requests that no Authenticator accepts are
+// rejected with a 401 status and a CodeUnauthenticated error, unless Optional
+// is set in which case they are passed on without a Principal.
+type AuthHandler struct {
+	Handler        http.Handler
+	Authenticators []Authenticator
+	Optional       bool
+}
+
+// NewAuthHandler returns a pointer to an AuthHandler requiring every request
+// to the handler to be authenticated by one of the authenticators.
+func NewAuthHandler(handler http.Handler, authenticators ...Authenticator) *AuthHandler {
+	auth := &AuthHandler{
+		Handler:        handler,
+		Authenticators: authenticators,
+	}
+	return auth
+}
+
+// ServeHTTP implements the http.Handler interface.
+func (auth *AuthHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	for _, authenticator := range auth.Authenticators {
+		principal, err := authenticator.Authenticate(r)
+		if err == errBodyTooLarge {
+			authError(w, http.StatusRequestEntityTooLarge, CodeParseError, err)
+			return
+		}
+		if err != nil {
+			unauthenticated(w, err)
+			return
+		}
+		if principal != nil {
+			auth.Handler.ServeHTTP(w, r.WithContext(WithPrincipal(r.Context(), principal)))
+			return
+		}
+	}
+
+	if !auth.Optional {
+		unauthenticated(w, ErrUnauthenticated)
+		return
+	}
+	auth.Handler.ServeHTTP(w, r)
+}
+
+func unauthenticated(w http.ResponseWriter, err error) {
+	authError(w, http.StatusUnauthorized, CodeUnauthenticated, err)
+}
+
+// authError writes an error response with the given http status.
+func authError(w http.ResponseWriter, status int, code int, err error) {
+	data, _ := Marshal(&Response{
+		Version: "2.0",
+		Error: &Error{
+			Code:    code,
+			Message: err.Error(),
+		},
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(data)
+}
+
+// Credentials add authentication to the requests made by a Client.
+type Credentials interface {
+	Apply(req *http.Request) error
+}
+
+// BasicCredentials authenticate requests with HTTP basic auth.
+type BasicCredentials struct {
+	Username string
+	Password string
+}
+
+// Apply implements the Credentials interface.
+func (credentials *BasicCredentials) Apply(req *http.Request) error {
+	req.SetBasicAuth(credentials.Username, credentials.Password)
+	return nil
+}
+
+// TokenCredentials authenticate requests with a bearer token, calling the
+// function for the token before each request so it may be refreshed.
+type TokenCredentials func() (string, error)
+
+// Apply implements the Credentials interface.
+func (fn TokenCredentials) Apply(req *http.Request) error {
+	token, err := fn()
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	return nil
+}
+
+// BearerToken returns Credentials authenticating requests with a fixed
+// bearer token.
+func BearerToken(token string) Credentials {
+	return TokenCredentials(func() (string, error) {
+		return token, nil
+	})
+}
+
+// HMACCredentials sign requests with a shared key for HMACAuthenticator.
+type HMACCredentials struct {
+	KeyID string
+	Key   []byte
+}
+
+// Apply implements the Credentials interface.
+func (credentials *HMACCredentials) Apply(req *http.Request) error {
+	var body []byte
+	if req.Body != nil {
+		var err error
+		body, err = ioutil.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return err
+		}
+		setRequestBody(req, body)
+	}
+
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	signature := signRequest(credentials.Key, req.Method, req.URL.RequestURI(), timestamp, body)
+
+	req.Header.Set("Authorization", fmt.Sprintf("%s %s:%s:%s", HMACScheme, credentials.KeyID, timestamp, base64.StdEncoding.EncodeToString(signature)))
+	return nil
+}
+
+// send applies the client's Credentials to the request and executes it.
+func (client *Client) send(req *http.Request) (*http.Response, error) {
+	if client.Credentials != nil {
+		err := client.Credentials.Apply(req)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return client.HTTPClient.Do(req)
+}
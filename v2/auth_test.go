@@ -0,0 +1,249 @@
+package jsonrpc
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newWhoAmIDispatcher() *MapDispatcher {
+	dispatcher := NewMapDispatcher()
+	dispatcher.Register("whoami", func(resp *Response, call *Call, req *http.Request) {
+		principal := PrincipalFromRequest(req)
+		if principal == nil {
+			resp.Result = "anonymous"
+			return
+		}
+		resp.Result = principal.Scheme + ":" + principal.Name
+	})
+	return dispatcher
+}
+
+func checkPassword(username string, password string) (*Principal, error) {
+	if username != "alice" || !SecureCompare(password, "secret") {
+		return nil, ErrInvalidCredentials
+	}
+	return &Principal{Name: username, Scheme: "basic"}, nil
+}
+
+func checkToken(token string) (*Principal, error) {
+	if !SecureCompare(token, "t0ken") {
+		return nil, ErrInvalidCredentials
+	}
+	return &Principal{Name: "service", Scheme: "bearer"}, nil
+}
+
+func newAuthServer(optional bool) *httptest.Server {
+	auth := NewAuthHandler(
+		&Handler{Dispatcher: newWhoAmIDispatcher()},
+		BasicAuthenticator(checkPassword),
+		BearerAuthenticator(checkToken),
+		&HMACAuthenticator{Keys: map[string][]byte{"backend": []byte("shared")}},
+	)
+	auth.Optional = optional
+	return httptest.NewServer(auth)
+}
+
+func TestAuthHandler(t *testing.T) {
+	server := newAuthServer(false)
+	defer server.Close()
+
+	tests := []struct {
+		credentials Credentials
+		expected    string
+	}{
+		{&BasicCredentials{Username: "alice", Password: "secret"}, "basic:alice"},
+		{BearerToken("t0ken"), "bearer:service"},
+		{&HMACCredentials{KeyID: "backend", Key: []byte("shared")}, HMACScheme + ":backend"},
+	}
+
+	for _, test := range tests {
+		client := NewClient()
+		client.Credentials = test.credentials
+
+		var result string
+		err := client.Call(server.URL, "whoami", []int{1, 2, 3}, &result)
+		assert.Nil(t, err)
+		assert.Equal(t, test.expected, result)
+	}
+}
+
+func TestAuthHandler_rejected(t *testing.T) {
+	server := newAuthServer(false)
+	defer server.Close()
+
+	tests := []Credentials{
+		nil,
+		&BasicCredentials{Username: "alice", Password: "wrong"},
+		BearerToken("wrong"),
+		&HMACCredentials{KeyID: "backend", Key: []byte("wrong")},
+		&HMACCredentials{KeyID: "unknown", Key: []byte("shared")},
+	}
+
+	for _, credentials := range tests {
+		client := NewClient()
+		client.Credentials = credentials
+
+		var result string
+		err := client.Call(server.URL, "whoami", nil, &result)
+		if assert.IsType(t, &Error{}, err) {
+			assert.Equal(t, CodeUnauthenticated, err.(*Error).Code)
+		}
+	}
+
+	response, err := http.Post(server.URL, "application/json", strings.NewReader(`{}`))
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusUnauthorized, response.StatusCode)
+}
+
+func TestAuthHandler_optional(t *testing.T) {
+	server := newAuthServer(true)
+	defer server.Close()
+
+	var result string
+	err := NewClient().Call(server.URL, "whoami", nil, &result)
+	assert.Nil(t, err)
+	assert.Equal(t, "anonymous", result)
+
+	// invalid credentials are still rejected
+	client := NewClient()
+	client.Credentials = BearerToken("wrong")
+	err = client.Call(server.URL, "whoami", nil, &result)
+	assert.Equal(t, CodeUnauthenticated, err.(*Error).Code)
+}
+
+func TestHMACAuthenticator(t *testing.T) {
+	auth := &HMACAuthenticator{
+		Keys:    map[string][]byte{"backend": []byte("shared")},
+		MaxSkew: time.Minute,
+	}
+	body := []byte(`{"jsonrpc": "2.0", "id": 1, "method": "whoami"}`)
+
+	sign := func(timestamp int64, body []byte) *http.Request {
+		r := httptest.NewRequest(http.MethodPost, "/rpc", bytes.NewReader(body))
+		ts := strconv.FormatInt(timestamp, 10)
+		signature := signRequest([]byte("shared"), http.MethodPost, "/rpc", ts, body)
+		r.Header.Set("Authorization", fmt.Sprintf("%s backend:%s:%x", HMACScheme, ts, signature))
+		return r
+	}
+
+	credentials := &HMACCredentials{KeyID: "backend", Key: []byte("shared")}
+	r := httptest.NewRequest(http.MethodPost, "/rpc", bytes.NewReader(body))
+	assert.Nil(t, credentials.Apply(r))
+
+	principal, err := auth.Authenticate(r)
+	assert.Nil(t, err)
+	assert.Equal(t, &Principal{Name: "backend", Scheme: HMACScheme}, principal)
+
+	// the body is left for the handler
+	rest := new(bytes.Buffer)
+	rest.ReadFrom(r.Body)
+	assert.Equal(t, body, rest.Bytes())
+
+	// the signature is hex rather than base64
+	_, err = auth.Authenticate(sign(time.Now().Unix(), body))
+	assert.Equal(t, ErrInvalidCredentials, err)
+
+	// tampered body
+	r = httptest.NewRequest(http.MethodPost, "/rpc", bytes.NewReader(body))
+	assert.Nil(t, credentials.Apply(r))
+	r.Body = httptest.NewRequest(http.MethodPost, "/rpc", strings.NewReader(`{}`)).Body
+	_, err = auth.Authenticate(r)
+	assert.Equal(t, ErrInvalidCredentials, err)
+
+	// tampered query of a GET request
+	r = httptest.NewRequest(http.MethodGet, "/rpc?method=whoami&id=1", nil)
+	assert.Nil(t, credentials.Apply(r))
+	principal, err = auth.Authenticate(r)
+	assert.Nil(t, err)
+	assert.NotNil(t, principal)
+
+	r.URL.RawQuery = "method=delete&id=1"
+	_, err = auth.Authenticate(r)
+	assert.Equal(t, ErrInvalidCredentials, err)
+
+	// stale timestamp
+	r = httptest.NewRequest(http.MethodPost, "/rpc", bytes.NewReader(body))
+	ts := strconv.FormatInt(time.Now().Add(-time.Hour).Unix(), 10)
+	r.Header.Set("Authorization", fmt.Sprintf("%s backend:%s:AAAA", HMACScheme, ts))
+	_, err = auth.Authenticate(r)
+	assert.Equal(t, ErrInvalidCredentials, err)
+
+	// other schemes are left for other authenticators
+	r = httptest.NewRequest(http.MethodPost, "/rpc", nil)
+	r.SetBasicAuth("alice", "secret")
+	principal, err = auth.Authenticate(r)
+	assert.Nil(t, principal)
+	assert.Nil(t, err)
+}
+
+func TestHMACAuthenticator_MaxBodySize(t *testing.T) {
+	auth := NewAuthHandler(
+		&Handler{Dispatcher: newWhoAmIDispatcher()},
+		&HMACAuthenticator{Keys: map[string][]byte{"backend": []byte("shared")}, MaxBodySize: 64},
+	)
+	credentials := &HMACCredentials{KeyID: "backend", Key: []byte("shared")}
+
+	body := `{"jsonrpc": "2.0", "id": 1, "method": "whoami"}`
+	r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+	assert.Nil(t, credentials.Apply(r))
+	w := httptest.NewRecorder()
+	auth.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), HMACScheme+":backend")
+
+	// the body is not read beyond the limit, signed or not
+	body = `{"jsonrpc": "2.0", "id": 1, "method": "whoami", "params": "` + strings.Repeat("a", 100) + `"}`
+	r = httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+	assert.Nil(t, credentials.Apply(r))
+	w = httptest.NewRecorder()
+	auth.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+
+	var resp Response
+	assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, CodeParseError, resp.Error.Code)
+}
+
+func TestTokenCredentials(t *testing.T) {
+	var calls int
+	credentials := TokenCredentials(func() (string, error) {
+		calls++
+		if calls > 1 {
+			return "", errors.New("token expired")
+		}
+		return "t0ken", nil
+	})
+
+	r := httptest.NewRequest(http.MethodPost, "/", nil)
+	assert.Nil(t, credentials.Apply(r))
+	assert.Equal(t, "Bearer t0ken", r.Header.Get("Authorization"))
+
+	assert.NotNil(t, credentials.Apply(r))
+}
+
+func TestWebSocketHandler_principal(t *testing.T) {
+	server := httptest.NewServer(NewAuthHandler(
+		&WebSocketHandler{Dispatcher: newWhoAmIDispatcher()},
+		BearerAuthenticator(checkToken),
+	))
+	defer server.Close()
+
+	client := NewWebSocketClient("ws" + strings.TrimPrefix(server.URL, "http"))
+	client.Header = http.Header{"Authorization": []string{"Bearer t0ken"}}
+	defer client.Close()
+
+	var result string
+	err := client.Call("whoami", nil, &result)
+	assert.Nil(t, err)
+	assert.Equal(t, "bearer:service", result)
+}
@@ -0,0 +1,151 @@
+package jsonrpc
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// CodeForbidden is the error code sent when the caller is not allowed to
+// call a method.
+const CodeForbidden int = -32003
+
+// ErrForbidden is returned by an Authorizer when the caller is not allowed to
+// call a method.
+var ErrForbidden = errors.New("jsonrpc: not allowed to call this method")
+
+// Policy holds the requirements for calling a method. The caller must have at
+// least one of Roles, when there are any, and every one of Scopes.
+type Policy struct {
+	Roles  []string
+	Scopes []string
+}
+
+// An Authorizer decides whether a principal may make a call to a method with
+// the given policy, returning a non nil error to deny it.
+type Authorizer interface {
+	Authorize(principal *Principal, call *Call, policy *Policy) error
+}
+
+// AuthorizerFunc adapts a function to the Authorizer interface.
+type AuthorizerFunc func(principal *Principal, call *Call, policy *Policy) error
+
+// Authorize implements the Authorizer interface.
+func (fn AuthorizerFunc) Authorize(principal *Principal, call *Call, policy *Policy) error {
+	return fn(principal, call, policy)
+}
+
+// PolicyAuthorizer is the default Authorizer, checking the principal's roles
+// and scopes against the policy.
+type PolicyAuthorizer struct{}
+
+// Authorize implements the Authorizer interface.
+func (PolicyAuthorizer) Authorize(principal *Principal, call *Call, policy *Policy) error {
+	if policy == nil {
+		return nil
+	}
+
+	if len(policy.Roles) > 0 {
+		allowed := false
+		for _, role := range policy.Roles {
+			if principal.HasRole(role) {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			return fmt.Errorf("jsonrpc: %s requires one of the roles %s", call.Method, strings.Join(policy.Roles, ", "))
+		}
+	}
+
+	for _, scope := range policy.Scopes {
+		if !principal.HasScope(scope) {
+			return fmt.Errorf("jsonrpc: %s requires the scope %s", call.Method, scope)
+		}
+	}
+	return nil
+}
+
+// RegisterWithPolicy registers a method that may only be called by
+// authenticated callers meeting the policy. A nil policy lets any
+// authenticated caller call the method.
+func (dispatcher *MapDispatcher) RegisterWithPolicy(name string, method Method, policy *Policy) error {
+	err := dispatcher.Register(name, method)
+	if err != nil {
+		return err
+	}
+
+	if policy == nil {
+		policy = &Policy{}
+	}
+
+	dispatcher.mtx.Lock()
+	defer dispatcher.mtx.Unlock()
+
+	dispatcher.policies[name] = policy
+	return nil
+}
+
+// SetAuthorizer sets the Authorizer used to check calls to methods with a
+// policy, replacing the default PolicyAuthorizer.
+func (dispatcher *MapDispatcher) SetAuthorizer(authorizer Authorizer) {
+	dispatcher.mtx.Lock()
+	defer dispatcher.mtx.Unlock()
+
+	dispatcher.authorizer = authorizer
+}
+
+// callAuthorizer is implemented by dispatchers that authorize calls, so
+// wrappers answering calls themselves can apply the same checks.
+type callAuthorizer interface {
+	authorize(call *Call, req *http.Request) *Error
+}
+
+// authorize checks the call against the method's policy, if it has one,
+// returning the error to respond with when it is denied.
+func (dispatcher *MapDispatcher) authorize(call *Call, req *http.Request) *Error {
+	dispatcher.mtx.RLock()
+	policy, ok := dispatcher.policies[call.Method]
+	_, registered := dispatcher.methods[call.Method]
+	authorizer := dispatcher.authorizer
+	dispatcher.mtx.RUnlock()
+
+	if !ok && !registered {
+		// the method may be in a mounted dispatcher with policies of its own
+		mounted, inner := dispatcher.mounted(call)
+		if mounted, isAuthorizer := mounted.(callAuthorizer); isAuthorizer {
+			return mounted.authorize(inner, req)
+		}
+	}
+
+	if !ok {
+		return nil
+	}
+
+	principal := PrincipalFromRequest(req)
+	if principal == nil {
+		return &Error{
+			Code:    CodeUnauthenticated,
+			Message: fmt.Sprintf("jsonrpc: %s requires an authenticated caller", call.Method),
+		}
+	}
+
+	if authorizer == nil {
+		authorizer = PolicyAuthorizer{}
+	}
+
+	err := authorizer.Authorize(principal, call, policy)
+	if err != nil {
+		return &Error{
+			Code:    CodeForbidden,
+			Message: err.Error(),
+		}
+	}
+	return nil
+}
+
+// RegisterWithPolicy adds the method with a policy to the DefaultDispatcher
+func RegisterWithPolicy(name string, method Method, policy *Policy) error {
+	return DefaultDispatcher.RegisterWithPolicy(name, method, policy)
+}
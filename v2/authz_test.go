@@ -0,0 +1,174 @@
+package jsonrpc
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newPolicyDispatcher(calls *int32) *MapDispatcher {
+	dispatcher := newNameDispatcher(calls, "public")
+	method := countCalls(calls, nameMethod)
+	dispatcher.RegisterWithPolicy("refund", method, &Policy{Roles: []string{"admin", "support"}})
+	dispatcher.RegisterWithPolicy("book", method, &Policy{Scopes: []string{"bookings:write", "payments"}})
+	return dispatcher
+}
+
+func TestMapDispatcher_RegisterWithPolicy(t *testing.T) {
+	var calls int32
+	dispatcher := newPolicyDispatcher(&calls)
+
+	support := &Principal{Name: "sam", Roles: []string{"support"}}
+	customer := &Principal{Name: "cat", Roles: []string{"customer"}, Scopes: []string{"bookings:write"}}
+	app := &Principal{Name: "app", Scopes: []string{"payments", "bookings:write"}}
+
+	assert.Nil(t, dispatchCall(dispatcher, nil, "public", "").Error)
+	assert.Nil(t, dispatchCall(dispatcher, requestAs(support), "refund", "").Error)
+	assert.Nil(t, dispatchCall(dispatcher, requestAs(app), "book", "").Error)
+	assert.Equal(t, int32(3), atomic.LoadInt32(&calls))
+
+	assert.Equal(t, CodeUnauthenticated, dispatchCall(dispatcher, nil, "refund", "").Error.Code)
+	assert.Equal(t, CodeForbidden, dispatchCall(dispatcher, requestAs(customer), "refund", "").Error.Code)
+	assert.Equal(t, CodeForbidden, dispatchCall(dispatcher, requestAs(customer), "book", "").Error.Code)
+	assert.Equal(t, CodeForbidden, dispatchCall(dispatcher, requestAs(support), "book", "").Error.Code)
+	assert.Equal(t, int32(3), atomic.LoadInt32(&calls))
+
+	assert.NotNil(t, dispatcher.RegisterWithPolicy("refund", nil, &Policy{}))
+}
+
+func TestMapDispatcher_RegisterWithPolicy_nil(t *testing.T) {
+	var calls int32
+	dispatcher := newPolicyDispatcher(&calls)
+	dispatcher.RegisterWithPolicy("account", func(resp *Response, call *Call, req *http.Request) {}, nil)
+
+	assert.Equal(t, CodeUnauthenticated, dispatchCall(dispatcher, nil, "account", "").Error.Code)
+	assert.Nil(t, dispatchCall(dispatcher, requestAs(&Principal{Name: "cat"}), "account", "").Error)
+
+	var policy *Policy
+	dispatcher.SetAuthorizer(AuthorizerFunc(func(principal *Principal, call *Call, p *Policy) error {
+		policy = p
+		return nil
+	}))
+	dispatchCall(dispatcher, requestAs(&Principal{Name: "cat"}), "account", "")
+	assert.Equal(t, &Policy{}, policy)
+}
+
+func TestMapDispatcher_SetAuthorizer(t *testing.T) {
+	var calls int32
+	dispatcher := newPolicyDispatcher(&calls)
+	dispatcher.SetAuthorizer(AuthorizerFunc(func(principal *Principal, call *Call, policy *Policy) error {
+		if principal.Name == "root" {
+			return nil
+		}
+		return PolicyAuthorizer{}.Authorize(principal, call, policy)
+	}))
+
+	assert.Nil(t, dispatchCall(dispatcher, requestAs(&Principal{Name: "root"}), "refund", "").Error)
+	assert.Equal(t, CodeForbidden, dispatchCall(dispatcher, requestAs(&Principal{Name: "bob"}), "refund", "").Error.Code)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+
+	dispatcher.SetAuthorizer(AuthorizerFunc(func(principal *Principal, call *Call, policy *Policy) error {
+		return errors.New("closed for maintenance")
+	}))
+	resp := dispatchCall(dispatcher, requestAs(&Principal{Name: "root"}), "refund", "")
+	assert.Equal(t, "closed for maintenance", resp.Error.Message)
+}
+
+func TestAuthorization_batch(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(NewAuthHandler(
+		&Handler{Dispatcher: newPolicyDispatcher(&calls)},
+		BearerAuthenticator(func(token string) (*Principal, error) {
+			return &Principal{Name: "sam", Roles: []string{"support"}}, nil
+		}),
+	))
+	defer server.Close()
+
+	req, _ := http.NewRequest(http.MethodPost, server.URL, strings.NewReader(`[
+		{"jsonrpc": "2.0", "id": "1", "method": "public"},
+		{"jsonrpc": "2.0", "id": "2", "method": "refund"},
+		{"jsonrpc": "2.0", "id": "3", "method": "book"}
+	]`))
+	req.Header.Set("Authorization", "Bearer t0ken")
+
+	response, err := http.DefaultClient.Do(req)
+	if !assert.Nil(t, err) {
+		t.FailNow()
+	}
+	defer response.Body.Close()
+
+	var responses []Response
+	assert.Nil(t, json.NewDecoder(response.Body).Decode(&responses))
+	assert.Len(t, responses, 3)
+	assert.Nil(t, responses[0].Error)
+	assert.Nil(t, responses[1].Error)
+	assert.Equal(t, CodeForbidden, responses[2].Error.Code)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&calls))
+}
+
+func TestCachingDispatcher_authorization(t *testing.T) {
+	var calls int32
+	dispatcher := NewCachingDispatcher(newPolicyDispatcher(&calls), 10)
+	dispatcher.CacheMethod("refund", time.Minute)
+
+	support := &Principal{Name: "sam", Roles: []string{"support"}}
+	assert.Nil(t, dispatchCall(dispatcher, requestAs(support), "refund", "").Error)
+
+	// the cached result is not served to callers the method would refuse
+	assert.Equal(t, CodeForbidden, dispatchCall(dispatcher, requestAs(&Principal{Name: "cat"}), "refund", "").Error.Code)
+	assert.Equal(t, CodeUnauthenticated, dispatchCall(dispatcher, nil, "refund", "").Error.Code)
+	assert.Nil(t, dispatchCall(dispatcher, requestAs(support), "refund", "").Error)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+}
+
+func TestCachingDispatcher_authorization_nested(t *testing.T) {
+	var calls int32
+	support := &Principal{Name: "sam", Roles: []string{"support"}}
+
+	inner := NewCachingDispatcher(newPolicyDispatcher(&calls), 10)
+	inner.CacheMethod("refund", time.Minute)
+	nested := NewCachingDispatcher(inner, 10)
+	nested.CacheMethod("refund", time.Minute)
+
+	mounted := NewMapDispatcher()
+	assert.Nil(t, mounted.Mount("billing", inner))
+	outer := NewCachingDispatcher(mounted, 10)
+	outer.CacheMethod("billing.refund", time.Minute)
+
+	group := NewMapDispatcher()
+	billing := group.Group("billing")
+	billing.RegisterWithPolicy("refund", countCalls(&calls, nameMethod), &Policy{Roles: []string{"support"}})
+	billing.Use(func(next Dispatcher) Dispatcher {
+		caching := NewCachingDispatcher(next, 10)
+		caching.CacheMethod("refund", time.Minute)
+		return caching
+	})
+	grouped := NewCachingDispatcher(group, 10)
+	grouped.CacheMethod("billing.refund", time.Minute)
+
+	tests := []struct {
+		dispatcher Dispatcher
+		method     string
+	}{
+		{nested, "refund"},
+		{outer, "billing.refund"},
+		{grouped, "billing.refund"},
+	}
+
+	for _, test := range tests {
+		assert.Nil(t, dispatchCall(test.dispatcher, requestAs(support), test.method, "").Error)
+		assert.Nil(t, dispatchCall(test.dispatcher, requestAs(support), test.method, "").Error)
+
+		// results cached at any level are not served to refused callers
+		assert.Equal(t, CodeUnauthenticated, dispatchCall(test.dispatcher, nil, test.method, "").Error.Code)
+		assert.Equal(t, CodeForbidden, dispatchCall(test.dispatcher, requestAs(&Principal{Name: "cat"}), test.method, "").Error.Code)
+	}
+	assert.Equal(t, int32(2), atomic.LoadInt32(&calls))
+}
@@ -3,25 +3,38 @@ package jsonrpc
 import (
 	"bytes"
 	"errors"
+	"fmt"
 	"net/http"
 	"strconv"
 	"sync"
 )
 
 type batchCall struct {
-	call   *clientCall
-	result interface{}
-	id     string
+	call     *clientCall
+	result   interface{}
+	id       string
+	err      error
+	answered bool
 }
 
 // Batch represents a collection of method calls that will be sent to the
 // server in a single HTTP call.
+//
+// When Stream is true the server is asked to send each response as soon as
+// its call completes, and OnResponse, if set, is called with the ID and error
+// of each call as its response arrives.
+//
+// JSON controls how the calls are encoded and results decoded, falling back
+// to IndentOutput and EscapeHTML when nil.
 type Batch struct {
 	order         []*batchCall
 	calls         map[string]*batchCall
 	id            int
 	mtx           *sync.Mutex
 	DiscardErrors bool
+	Stream        bool
+	OnResponse    func(id string, err error)
+	JSON          *JSONCodec
 }
 
 func (batch *Batch) nextID() string {
@@ -30,16 +43,68 @@ func (batch *Batch) nextID() string {
 	return id
 }
 
-func (batch *Batch) resultForID(rawID interface{}) (interface{}, bool) {
+func (batch *Batch) callForID(rawID interface{}) (*batchCall, bool) {
 	id, ok := rawID.(string)
 	if !ok {
 		return nil, false
 	}
 	call, ok := batch.calls[id]
+	return call, ok
+}
+
+// handleResponse deserialises a response into the result of its call. It
+// returns the first error encountered unless DiscardErrors is set.
+func (batch *Batch) handleResponse(resp *clientResponse) error {
+	call, ok := batch.callForID(resp.ID)
 	if !ok {
-		return nil, false
+		if batch.DiscardErrors {
+			return nil
+		}
+		err := fmt.Errorf("jsonrpc: unable to find a call with the response ID %s", resp.ID)
+		return err
+	}
+	call.answered = true
+
+	if resp.Error != nil {
+		call.err = resp.Error
+	} else {
+		call.err = batch.JSON.Unmarshal(resp.Result, call.result)
+	}
+
+	if batch.OnResponse != nil {
+		batch.OnResponse(call.id, call.err)
+	}
+
+	if batch.DiscardErrors {
+		return nil
+	}
+	return call.err
+}
+
+// Err returns the error encountered by an individual call in the batch once
+// it has been executed, or an error if the server never answered it.
+func (batch *Batch) Err(id string) error {
+	batch.mtx.Lock()
+	defer batch.mtx.Unlock()
+
+	call, ok := batch.calls[id]
+	if !ok {
+		return fmt.Errorf("jsonrpc: no call with ID %s in batch", id)
+	}
+	if call.err != nil {
+		return call.err
 	}
-	return call.result, true
+	if !call.answered {
+		return fmt.Errorf("jsonrpc: no response received for call with ID %s", id)
+	}
+	return nil
+}
+
+// Len returns the number of calls in the batch.
+func (batch *Batch) Len() int {
+	batch.mtx.Lock()
+	defer batch.mtx.Unlock()
+	return len(batch.order)
 }
 
 // AddCall adds a call to a Batch. Returns the id of the call.
@@ -86,7 +151,7 @@ func (batch *Batch) NewRequest(url string) (*http.Request, error) {
 		calls = append(calls, v.call)
 	}
 
-	data, err := Marshal(calls)
+	data, err := batch.JSON.Marshal(calls)
 
 	if err != nil {
 		return nil, err
@@ -100,6 +165,9 @@ func (batch *Batch) NewRequest(url string) (*http.Request, error) {
 	}
 
 	req.Header.Set("Content-Type", "application/json")
+	if batch.Stream {
+		req.Header.Set("Accept", StreamedBatchContentType)
+	}
 	return req, nil
 }
 
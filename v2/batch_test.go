@@ -36,3 +36,13 @@ func TestBatch_no_calls(t *testing.T) {
 	_, err := batch.NewRequest("https://foobar.com")
 	assert.NotNil(t, err)
 }
+
+func TestBatch_stream(t *testing.T) {
+	batch := NewBatch()
+	batch.Stream = true
+	batch.AddCall("add", []int{1, 2, 3}, nil)
+
+	req, err := batch.NewRequest("https://foobar.com")
+	assert.Nil(t, err)
+	assert.Equal(t, StreamedBatchContentType, req.Header.Get("Accept"))
+}
@@ -0,0 +1,233 @@
+package jsonrpc
+
+import (
+	"bytes"
+	"container/list"
+	"encoding/json"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+)
+
+// A Cache stores the raw JSON results of method calls.
+//
+// Implementations must be safe for concurrent use. Keys do not identify the
+// caller, so a Cache must only be shared by clients with the same
+// Credentials.
+type Cache interface {
+	Get(key string) ([]byte, bool)
+	Set(key string, value []byte, ttl time.Duration)
+}
+
+type lruEntry struct {
+	key     string
+	value   []byte
+	expires time.Time
+}
+
+// LRUCache is an in-memory Cache holding a fixed number of entries. When full
+// the least recently used entry is evicted to make room for a new one.
+type LRUCache struct {
+	size    int
+	entries map[string]*list.Element
+	order   *list.List
+	mtx     *sync.Mutex
+}
+
+// NewLRUCache returns a pointer to an LRUCache holding at most size entries.
+// A size of zero or less places no limit on the number of entries.
+func NewLRUCache(size int) *LRUCache {
+	cache := &LRUCache{
+		size:    size,
+		entries: make(map[string]*list.Element),
+		order:   list.New(),
+		mtx:     new(sync.Mutex),
+	}
+	return cache
+}
+
+// Get returns the value stored for the key if it has not expired.
+func (cache *LRUCache) Get(key string) ([]byte, bool) {
+	cache.mtx.Lock()
+	defer cache.mtx.Unlock()
+
+	element, ok := cache.entries[key]
+	if !ok {
+		return nil, false
+	}
+
+	entry := element.Value.(*lruEntry)
+	if !entry.expires.IsZero() && time.Now().After(entry.expires) {
+		cache.remove(element)
+		return nil, false
+	}
+
+	cache.order.MoveToFront(element)
+	return entry.value, true
+}
+
+// Set stores the value for the key for the given time to live. A ttl of zero
+// or less never expires.
+func (cache *LRUCache) Set(key string, value []byte, ttl time.Duration) {
+	cache.mtx.Lock()
+	defer cache.mtx.Unlock()
+
+	var expires time.Time
+	if ttl > 0 {
+		expires = time.Now().Add(ttl)
+	}
+
+	element, ok := cache.entries[key]
+	if ok {
+		entry := element.Value.(*lruEntry)
+		entry.value = value
+		entry.expires = expires
+		cache.order.MoveToFront(element)
+		return
+	}
+
+	element = cache.order.PushFront(&lruEntry{
+		key:     key,
+		value:   value,
+		expires: expires,
+	})
+	cache.entries[key] = element
+
+	if cache.size > 0 && cache.order.Len() > cache.size {
+		cache.remove(cache.order.Back())
+	}
+}
+
+// Delete removes the entry for the key.
+func (cache *LRUCache) Delete(key string) {
+	cache.mtx.Lock()
+	defer cache.mtx.Unlock()
+
+	element, ok := cache.entries[key]
+	if ok {
+		cache.remove(element)
+	}
+}
+
+// deletePrefix removes every entry whose key starts with prefix.
+func (cache *LRUCache) deletePrefix(prefix string) {
+	cache.mtx.Lock()
+	defer cache.mtx.Unlock()
+
+	for key, element := range cache.entries {
+		if strings.HasPrefix(key, prefix) {
+			cache.remove(element)
+		}
+	}
+}
+
+// Purge removes every entry.
+func (cache *LRUCache) Purge() {
+	cache.mtx.Lock()
+	defer cache.mtx.Unlock()
+
+	cache.entries = make(map[string]*list.Element)
+	cache.order.Init()
+}
+
+// Len returns the number of entries, including any that have expired but not
+// yet been evicted.
+func (cache *LRUCache) Len() int {
+	cache.mtx.Lock()
+	defer cache.mtx.Unlock()
+	return cache.order.Len()
+}
+
+func (cache *LRUCache) remove(element *list.Element) {
+	cache.order.Remove(element)
+	delete(cache.entries, element.Value.(*lruEntry).key)
+}
+
+// canonicalParams encodes params as JSON with object keys sorted and
+// insignificant whitespace removed, so that equal params always encode the
+// same way.
+func canonicalParams(params interface{}) ([]byte, error) {
+	data, err := json.Marshal(params)
+	if err != nil {
+		return nil, err
+	}
+	return canonicalJSON(data)
+}
+
+// canonicalJSON re-encodes raw JSON with object keys sorted.
+func canonicalJSON(data []byte) ([]byte, error) {
+	if len(bytes.TrimSpace(data)) == 0 {
+		return []byte("null"), nil
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+
+	var v interface{}
+	err := decoder.Decode(&v)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(v)
+}
+
+// cacheKey returns the key under which the result of a call is cached.
+func cacheKey(url string, method string, params interface{}) (string, error) {
+	canonical, err := canonicalParams(params)
+	if err != nil {
+		return "", err
+	}
+	return url + "\x00" + method + "\x00" + string(canonical), nil
+}
+
+// errFlightPanicked is returned to calls waiting on a flight whose call
+// panicked.
+var errFlightPanicked = errors.New("jsonrpc: shared call panicked")
+
+type flight struct {
+	done  chan struct{}
+	value []byte
+	err   error
+}
+
+// flightGroup de-duplicates concurrent calls with the same key so only one is
+// made and the rest share its result.
+type flightGroup struct {
+	flights map[string]*flight
+	mtx     *sync.Mutex
+}
+
+func newFlightGroup() *flightGroup {
+	return &flightGroup{
+		flights: make(map[string]*flight),
+		mtx:     new(sync.Mutex),
+	}
+}
+
+// do calls fn unless a call with the same key is already in flight, in which
+// case it waits for that call and returns its result.
+func (group *flightGroup) do(key string, fn func() ([]byte, error)) ([]byte, error) {
+	group.mtx.Lock()
+	f, ok := group.flights[key]
+	if ok {
+		group.mtx.Unlock()
+		<-f.done
+		return f.value, f.err
+	}
+
+	// the error is left for the waiters when fn panics
+	f = &flight{done: make(chan struct{}), err: errFlightPanicked}
+	group.flights[key] = f
+	group.mtx.Unlock()
+
+	defer func() {
+		group.mtx.Lock()
+		delete(group.flights, key)
+		group.mtx.Unlock()
+		close(f.done)
+	}()
+
+	f.value, f.err = fn()
+	return f.value, f.err
+}
@@ -0,0 +1,275 @@
+package jsonrpc
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLRUCache(t *testing.T) {
+	cache := NewLRUCache(2)
+
+	cache.Set("a", []byte("1"), 0)
+	cache.Set("b", []byte("2"), 0)
+
+	value, ok := cache.Get("a")
+	assert.True(t, ok)
+	assert.Equal(t, "1", string(value))
+
+	// b is now the least recently used
+	cache.Set("c", []byte("3"), 0)
+
+	_, ok = cache.Get("b")
+	assert.False(t, ok)
+	_, ok = cache.Get("a")
+	assert.True(t, ok)
+	_, ok = cache.Get("c")
+	assert.True(t, ok)
+	assert.Equal(t, 2, cache.Len())
+
+	cache.Set("a", []byte("4"), 0)
+	value, _ = cache.Get("a")
+	assert.Equal(t, "4", string(value))
+
+	cache.Delete("a")
+	_, ok = cache.Get("a")
+	assert.False(t, ok)
+
+	cache.Purge()
+	assert.Equal(t, 0, cache.Len())
+}
+
+func TestLRUCache_expiry(t *testing.T) {
+	cache := NewLRUCache(0)
+
+	cache.Set("a", []byte("1"), time.Millisecond)
+	cache.Set("b", []byte("2"), time.Hour)
+	time.Sleep(5 * time.Millisecond)
+
+	_, ok := cache.Get("a")
+	assert.False(t, ok)
+	_, ok = cache.Get("b")
+	assert.True(t, ok)
+}
+
+func TestCanonicalParams(t *testing.T) {
+	a, err := canonicalParams(map[string]interface{}{"b": 1, "a": []int{1, 2}})
+	assert.Nil(t, err)
+
+	b, err := canonicalJSON([]byte(`{ "a": [1, 2], "b": 1 }`))
+	assert.Nil(t, err)
+
+	assert.Equal(t, `{"a":[1,2],"b":1}`, string(a))
+	assert.Equal(t, string(a), string(b))
+
+	c, err := canonicalJSON([]byte(`12345678901234567890`))
+	assert.Nil(t, err)
+	assert.Equal(t, `12345678901234567890`, string(c))
+
+	d, err := canonicalJSON(nil)
+	assert.Nil(t, err)
+	assert.Equal(t, `null`, string(d))
+}
+
+func TestFlightGroup(t *testing.T) {
+	group := newFlightGroup()
+	release := make(chan struct{})
+	var calls int32
+
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			value, err := group.do("key", func() ([]byte, error) {
+				atomic.AddInt32(&calls, 1)
+				<-release
+				return []byte("value"), nil
+			})
+			assert.Nil(t, err)
+			assert.Equal(t, "value", string(value))
+		}()
+	}
+
+	time.Sleep(10 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+}
+
+func TestFlightGroup_panic(t *testing.T) {
+	group := newFlightGroup()
+	release := make(chan struct{})
+
+	panicked := make(chan interface{})
+	go func() {
+		defer func() {
+			panicked <- recover()
+		}()
+		group.do("key", func() ([]byte, error) {
+			<-release
+			panic("boom")
+		})
+	}()
+
+	time.Sleep(10 * time.Millisecond)
+	waited := make(chan error)
+	go func() {
+		_, err := group.do("key", func() ([]byte, error) {
+			return []byte("value"), nil
+		})
+		waited <- err
+	}()
+
+	time.Sleep(10 * time.Millisecond)
+	close(release)
+	assert.Equal(t, "boom", <-panicked)
+	assert.Equal(t, errFlightPanicked, <-waited)
+
+	// later calls are not left waiting on the failed flight
+	value, err := group.do("key", func() ([]byte, error) {
+		return []byte("value"), nil
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, "value", string(value))
+}
+
+func TestClient_call_cached(t *testing.T) {
+	var calls int32
+	dispatcher := NewMapDispatcher()
+	dispatcher.Register("currency", func(resp *Response, call *Call, req *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		var code string
+		call.UnmarshalParams(&code)
+		resp.Result = code + "!"
+	})
+
+	server := httptest.NewServer(&Handler{Dispatcher: dispatcher})
+	defer server.Close()
+
+	client := NewClient()
+	client.Cache = NewLRUCache(10)
+	client.CacheTTL = map[string]time.Duration{"currency": time.Minute}
+
+	for i := 0; i < 3; i++ {
+		var result string
+		err := client.Call(server.URL, "currency", "gbp", &result)
+		assert.Nil(t, err)
+		assert.Equal(t, "gbp!", result)
+	}
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+
+	var result string
+	err := client.Call(server.URL, "currency", "eur", &result)
+	assert.Nil(t, err)
+	assert.Equal(t, "eur!", result)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&calls))
+}
+
+func TestClient_call_cached_without_result(t *testing.T) {
+	var calls int32
+	dispatcher := NewMapDispatcher()
+	dispatcher.Register("currency", func(resp *Response, call *Call, req *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		resp.Result = "gbp"
+	})
+
+	server := httptest.NewServer(&Handler{Dispatcher: dispatcher})
+	defer server.Close()
+
+	client := NewClient()
+	client.Cache = NewLRUCache(10)
+	client.CacheTTL = map[string]time.Duration{"currency": time.Minute}
+
+	// the result is cached even when it is dropped
+	assert.Nil(t, client.Call(server.URL, "currency", nil, nil))
+	assert.Nil(t, client.Call(server.URL, "currency", nil, nil))
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+}
+
+func TestClient_call_cached_shared(t *testing.T) {
+	var calls int32
+	release := make(chan struct{})
+	dispatcher := NewMapDispatcher()
+	dispatcher.Register("currency", func(resp *Response, call *Call, req *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		<-release
+		resp.Result = "gbp"
+	})
+
+	server := httptest.NewServer(&Handler{Dispatcher: dispatcher})
+	defer server.Close()
+
+	// a client made without NewClient still shares calls in flight
+	client := &Client{
+		HTTPClient: http.DefaultClient,
+		Cache:      NewLRUCache(10),
+		CacheTTL:   map[string]time.Duration{"currency": time.Minute},
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			var result string
+			err := client.Call(server.URL, "currency", nil, &result)
+			assert.Nil(t, err)
+			assert.Equal(t, "gbp", result)
+		}()
+	}
+
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+}
+
+func TestClient_call_cached_errors(t *testing.T) {
+	var calls int32
+	dispatcher := NewMapDispatcher()
+	dispatcher.Register("currency", func(resp *Response, call *Call, req *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		resp.Error = &Error{Code: CodeInvalidParameters, Message: "nope"}
+	})
+
+	server := httptest.NewServer(&Handler{Dispatcher: dispatcher})
+	defer server.Close()
+
+	client := NewClient()
+	client.Cache = NewLRUCache(10)
+	client.CacheTTL = map[string]time.Duration{"currency": time.Minute}
+
+	var result string
+	assert.NotNil(t, client.Call(server.URL, "currency", "gbp", &result))
+	assert.NotNil(t, client.Call(server.URL, "currency", "gbp", &result))
+	assert.Equal(t, int32(2), atomic.LoadInt32(&calls))
+}
+
+func TestClient_call_uncached_method(t *testing.T) {
+	var calls int32
+	dispatcher := NewMapDispatcher()
+	dispatcher.Register("book", func(resp *Response, call *Call, req *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		resp.Result = true
+	})
+
+	server := httptest.NewServer(&Handler{Dispatcher: dispatcher})
+	defer server.Close()
+
+	client := NewClient()
+	client.Cache = NewLRUCache(10)
+	client.CacheTTL = map[string]time.Duration{"currency": time.Minute}
+
+	var result bool
+	client.Call(server.URL, "book", nil, &result)
+	client.Call(server.URL, "book", nil, &result)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&calls))
+}
@@ -2,6 +2,7 @@ package jsonrpc
 
 import (
 	"encoding/json"
+	"fmt"
 )
 
 // Call contains a target method and the parameters that should be deserilised
@@ -15,11 +16,50 @@ type Call struct {
 	ID      interface{}     `json:"id"`
 	Method  string          `json:"method"`
 	Params  json.RawMessage `json:"params"`
+	// origin is the call as it was received when this is a copy naming a
+	// method within a group or mounted dispatcher.
+	origin *Call
+}
+
+// received returns the call as it was received, before any group or mount
+// prefix was removed from its method.
+func (call *Call) received() *Call {
+	if call.origin != nil {
+		return call.origin
+	}
+	return call
 }
 
 // UnmarshalParams unmarshals the calls parameters into the given interface.
 func (call Call) UnmarshalParams(v interface{}) error {
-	return json.Unmarshal(call.Params, v)
+	return DefaultJSONEngine.Unmarshal(call.Params, v)
+}
+
+// UnmarshalPositionalParams unmarshals an array of parameters into each of the
+// given interfaces in turn. Interfaces beyond the end of the array are left as
+// they are, and missing or null parameters leave all of them.
+func (call Call) UnmarshalPositionalParams(v ...interface{}) error {
+	if len(call.Params) == 0 {
+		return nil
+	}
+
+	var params []json.RawMessage
+	err := DefaultJSONEngine.Unmarshal(call.Params, &params)
+	if err != nil {
+		return err
+	}
+
+	if len(params) > len(v) {
+		return fmt.Errorf("jsonrpc: expected at most %d parameters but got %d", len(v), len(params))
+	}
+
+	for i, param := range params {
+		err = DefaultJSONEngine.Unmarshal(param, v[i])
+		if err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 type clientCall struct {
@@ -28,3 +68,9 @@ type clientCall struct {
 	Method  string      `json:"method"`
 	Params  interface{} `json:"params"`
 }
+
+type clientNotification struct {
+	Version string      `json:"jsonrpc"`
+	Method  string      `json:"method"`
+	Params  interface{} `json:"params"`
+}
@@ -36,3 +36,27 @@ func TestCall_UnmarshalParams_with_bad_data(t *testing.T) {
 
 	assert.NotNil(t, err)
 }
+
+func TestCall_UnmarshalPositionalParams(t *testing.T) {
+	call := Call{
+		Params: json.RawMessage(`["apollo", [1, 2]]`),
+	}
+
+	var name string
+	var ids []int
+	limit := 10
+
+	err := call.UnmarshalPositionalParams(&name, &ids, &limit)
+
+	if assert.Nil(t, err) {
+		assert.Equal(t, "apollo", name)
+		assert.Equal(t, []int{1, 2}, ids)
+		assert.Equal(t, 10, limit)
+	}
+
+	assert.NotNil(t, call.UnmarshalPositionalParams(&name))
+	assert.NotNil(t, call.UnmarshalPositionalParams(&limit, &ids))
+	assert.Nil(t, Call{}.UnmarshalPositionalParams(&name))
+	assert.Nil(t, Call{Params: json.RawMessage(`null`)}.UnmarshalPositionalParams(&name))
+	assert.NotNil(t, Call{Params: json.RawMessage(`{"name": "apollo"}`)}.UnmarshalPositionalParams(&name))
+}
@@ -0,0 +1,32 @@
+// Package cbor provides a CBOR Codec for JSONRPC handlers and clients.
+package cbor
+
+import (
+	"github.com/fxamacker/cbor/v2"
+)
+
+// ContentType is the content type of CBOR bodies.
+const ContentType = "application/cbor"
+
+// Codec encodes bodies as CBOR. Implements the jsonrpc.Codec interface.
+type Codec struct{}
+
+// NewCodec returns a pointer to a CBOR Codec.
+func NewCodec() *Codec {
+	return &Codec{}
+}
+
+// ContentType returns the content type of CBOR bodies.
+func (codec *Codec) ContentType() string {
+	return ContentType
+}
+
+// Marshal returns the CBOR encoding of v.
+func (codec *Codec) Marshal(v interface{}) ([]byte, error) {
+	return cbor.Marshal(v)
+}
+
+// Unmarshal decodes the CBOR data into v.
+func (codec *Codec) Unmarshal(data []byte, v interface{}) error {
+	return cbor.Unmarshal(data, v)
+}
@@ -0,0 +1,193 @@
+package cbor
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/fxamacker/cbor/v2"
+	"github.com/ingresso-group/gojsonrpc/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+type order struct {
+	Venue   string `json:"venue"`
+	Tickets []int  `json:"tickets"`
+}
+
+func newDispatcher() *jsonrpc.MapDispatcher {
+	dispatcher := jsonrpc.NewMapDispatcher()
+	dispatcher.Register("book", func(resp *jsonrpc.Response, call *jsonrpc.Call, req *http.Request) {
+		var params order
+		err := call.UnmarshalParams(&params)
+		if err != nil {
+			resp.Error = &jsonrpc.Error{Code: jsonrpc.CodeInvalidParameters, Message: err.Error()}
+			return
+		}
+		resp.Result = &params
+	})
+	return dispatcher
+}
+
+func TestHandler(t *testing.T) {
+	handler := &jsonrpc.Handler{
+		Dispatcher: newDispatcher(),
+		Codecs:     []jsonrpc.Codec{NewCodec()},
+	}
+
+	body, err := cbor.Marshal(map[string]interface{}{
+		"jsonrpc": "2.0",
+		"id":      1,
+		"method":  "book",
+		"params":  map[string]interface{}{"venue": "palladium", "tickets": []int{4, 5}},
+	})
+	assert.Nil(t, err)
+
+	r := httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(body))
+	r.Header.Set("Content-Type", ContentType)
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+
+	assert.Equal(t, ContentType, w.Header().Get("Content-Type"))
+
+	var resp struct {
+		Version string `cbor:"jsonrpc"`
+		ID      int    `cbor:"id"`
+		Result  struct {
+			Venue   string `cbor:"venue"`
+			Tickets []int  `cbor:"tickets"`
+		} `cbor:"result"`
+	}
+	data, _ := ioutil.ReadAll(w.Body)
+	assert.Nil(t, cbor.Unmarshal(data, &resp))
+	assert.Equal(t, "2.0", resp.Version)
+	assert.Equal(t, 1, resp.ID)
+	assert.Equal(t, "palladium", resp.Result.Venue)
+	assert.Equal(t, []int{4, 5}, resp.Result.Tickets)
+}
+
+func TestClient(t *testing.T) {
+	var contentTypes []string
+	handler := &jsonrpc.Handler{
+		Dispatcher: newDispatcher(),
+		Codecs:     []jsonrpc.Codec{NewCodec()},
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		contentTypes = append(contentTypes, r.Header.Get("Content-Type"))
+		handler.ServeHTTP(w, r)
+	}))
+	defer server.Close()
+
+	client := jsonrpc.NewClient()
+	client.Codec = NewCodec()
+
+	var result order
+	err := client.Call(server.URL, "book", &order{Venue: "palladium", Tickets: []int{4, 5}}, &result)
+	assert.Nil(t, err)
+	assert.Equal(t, order{Venue: "palladium", Tickets: []int{4, 5}}, result)
+
+	var a, b order
+	batch := jsonrpc.NewBatch()
+	batch.AddCall("book", &order{Venue: "a"}, &a)
+	batch.AddCall("book", &order{Venue: "b"}, &b)
+	assert.Nil(t, client.Batch(server.URL, batch))
+	assert.Equal(t, "a", a.Venue)
+	assert.Equal(t, "b", b.Venue)
+
+	err = client.Call(server.URL, "book", "not an order", &result)
+	assert.Equal(t, jsonrpc.CodeInvalidParameters, err.(*jsonrpc.Error).Code)
+
+	assert.Equal(t, []string{ContentType, ContentType, ContentType}, contentTypes)
+}
+
+func TestClient_json_server(t *testing.T) {
+	server := httptest.NewServer(&jsonrpc.Handler{Dispatcher: newDispatcher()})
+	defer server.Close()
+
+	client := jsonrpc.NewClient()
+	client.Codec = NewCodec()
+
+	// the server does not understand CBOR and answers in JSON
+	var result order
+	err := client.Call(server.URL, "book", &order{Venue: "palladium"}, &result)
+	assert.Equal(t, jsonrpc.CodeParseError, err.(*jsonrpc.Error).Code)
+}
+
+func TestHandler_bytes(t *testing.T) {
+	dispatcher := jsonrpc.NewMapDispatcher()
+	dispatcher.Register("echo", func(resp *jsonrpc.Response, call *jsonrpc.Call, req *http.Request) {
+		var params struct {
+			Data []byte `json:"data"`
+		}
+		call.UnmarshalParams(&params)
+		assert.Equal(t, []byte{1, 2, 3}, params.Data)
+		resp.Result = params.Data
+	})
+	handler := &jsonrpc.Handler{
+		Dispatcher: dispatcher,
+		Codecs:     []jsonrpc.Codec{NewCodec()},
+	}
+
+	body, err := cbor.Marshal(map[string]interface{}{
+		"jsonrpc": "2.0",
+		"id":      1,
+		"method":  "echo",
+		"params":  map[string]interface{}{"data": []byte{1, 2, 3}},
+	})
+	assert.Nil(t, err)
+
+	r := httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(body))
+	r.Header.Set("Content-Type", ContentType)
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+
+	// byte strings pass through JSON, so results hold them base64 encoded
+	var resp struct {
+		Result interface{} `cbor:"result"`
+	}
+	assert.Nil(t, cbor.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, "AQID", resp.Result)
+}
+
+func batchCalls() []interface{} {
+	calls := make([]interface{}, 20)
+	for i := range calls {
+		calls[i] = map[string]interface{}{
+			"jsonrpc": "2.0",
+			"id":      i,
+			"method":  "book",
+			"params":  map[string]interface{}{"venue": "palladium", "tickets": []int{4, 5, 6, 7, 8, 9}},
+		}
+	}
+	return calls
+}
+
+func benchmarkHandler(b *testing.B, contentType string, body []byte) {
+	handler := &jsonrpc.Handler{
+		Dispatcher:         newDispatcher(),
+		DisableCompression: true,
+		JSON:               &jsonrpc.JSONCodec{},
+		Codecs:             []jsonrpc.Codec{NewCodec()},
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		r := httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(body))
+		r.Header.Set("Content-Type", contentType)
+		handler.ServeHTTP(httptest.NewRecorder(), r)
+	}
+}
+
+func BenchmarkHandler_json(b *testing.B) {
+	body, _ := json.Marshal(batchCalls())
+	benchmarkHandler(b, "application/json", body)
+}
+
+func BenchmarkHandler_cbor(b *testing.B) {
+	body, _ := cbor.Marshal(batchCalls())
+	benchmarkHandler(b, ContentType, body)
+}
@@ -2,10 +2,12 @@ package jsonrpc
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
-	"fmt"
-	"io/ioutil"
+	"io"
 	"net/http"
+	"sync"
+	"time"
 )
 
 var (
@@ -15,8 +17,37 @@ var (
 
 // Client is a JSONRPC client that faciliates the calling of methods on a
 // JSONRPC server.
+//
+// When Cache is set, the results of calls made with Call to the methods in
+// CacheTTL are cached for the given time, keyed by URL, method and params.
+// Identical calls to those methods made while one is already in flight wait
+// for its result rather than making another request. The key does not
+// include Credentials, so a Cache must not be shared by clients calling as
+// different principals.
+//
+// When CompressRequests is set, batch request bodies of at least
+// CompressionThreshold bytes (DefaultCompressionThreshold when zero) are sent
+// gzip compressed. Compressed responses are always decoded.
+//
+// JSON controls how calls are encoded and results decoded, falling back to
+// IndentOutput and EscapeHTML when nil. Batches use their own JSONCodec.
+//
+// When Codec is set, calls and batches are sent encoded with it rather than
+// as JSON. Responses are decoded according to their Content-Type, so servers
+// without the codec can still answer in JSON.
+//
+// Credentials, when set, are applied to every request the client makes.
 type Client struct {
-	HTTPClient *http.Client
+	HTTPClient           *http.Client
+	Cache                Cache
+	CacheTTL             map[string]time.Duration
+	CompressRequests     bool
+	CompressionThreshold int
+	JSON                 *JSONCodec
+	Codec                Codec
+	Credentials          Credentials
+	flights              *flightGroup
+	flightsOnce          sync.Once
 }
 
 // NewClient creates a new client that makes use of the http.DefaultClient as
@@ -28,48 +59,129 @@ func NewClient() *Client {
 	return client
 }
 
-func (client *Client) do(req *http.Request, result interface{}) error {
+// decodeRawResponse returns the raw result of a single response, or the
+// response's error if it has one.
+func decodeRawResponse(body []byte, engine JSONEngine) (json.RawMessage, error) {
+	var resp clientResponse
+
+	err := engine.Unmarshal(body, &resp)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.Error != nil {
+		return nil, resp.Error
+	}
+
+	return resp.Result, nil
+}
 
-	rawresp, err := client.HTTPClient.Do(req)
+// decodeResponse deserialises the result of a single response into result
+// with the codec, returning the response's error if it has one.
+func decodeResponse(body []byte, result interface{}, codec *JSONCodec) error {
+	raw, err := decodeRawResponse(body, codec.engine())
 
 	if err != nil {
 		return err
 	}
 
-	body, err := ioutil.ReadAll(rawresp.Body)
+	return codec.Unmarshal(raw, result)
+}
+
+func (client *Client) doRaw(req *http.Request) (json.RawMessage, error) {
+
+	rawresp, err := client.send(req)
+
+	if err != nil {
+		return nil, err
+	}
+	defer rawresp.Body.Close()
+
+	body, err := client.readBody(rawresp)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return decodeRawResponse(body, client.JSON.engine())
+}
+
+func (client *Client) do(req *http.Request, result interface{}) error {
+	raw, err := client.doRaw(req)
 
 	if err != nil {
 		return err
 	}
 
-	var resp clientResponse
+	return client.unmarshalResult(raw, result)
+}
 
-	err = json.Unmarshal(body, &resp)
+// unmarshalResult decodes the raw result into result, dropping it when the
+// caller has nowhere to put it.
+func (client *Client) unmarshalResult(raw json.RawMessage, result interface{}) error {
+	if result == nil {
+		return nil
+	}
+	return client.JSON.Unmarshal(raw, result)
+}
 
+// callCached makes a call through the client's Cache, only making a request
+// when the result is not already cached or being fetched. The request is
+// made with the context of the call that makes it.
+func (client *Client) callCached(ctx context.Context, url string, method string, params interface{}, result interface{}, ttl time.Duration) error {
+	key, err := cacheKey(url, method, params)
 	if err != nil {
 		return err
 	}
 
-	if resp.Error != nil {
-		return resp.Error
+	raw, ok := client.Cache.Get(key)
+	if ok {
+		return client.unmarshalResult(raw, result)
 	}
 
-	err = json.Unmarshal(resp.Result, result)
+	fetch := func() ([]byte, error) {
+		req, err := client.newRequest(url, method, params)
+		if err != nil {
+			return nil, err
+		}
+
+		raw, err := client.doRaw(req.WithContext(ctx))
+		if err != nil {
+			return nil, err
+		}
 
+		client.Cache.Set(key, raw, ttl)
+		return raw, nil
+	}
+
+	// the group is made here so clients not created with NewClient share
+	// calls too
+	client.flightsOnce.Do(func() {
+		client.flights = newFlightGroup()
+	})
+
+	raw, err = client.flights.do(key, fetch)
 	if err != nil {
 		return err
 	}
-	return nil
+
+	return client.unmarshalResult(raw, result)
 }
 
 func (client *Client) doBatch(req *http.Request, batch *Batch) error {
-	rawresp, err := client.HTTPClient.Do(req)
+	rawresp, err := client.send(req)
 
 	if err != nil {
 		return err
 	}
+	defer rawresp.Body.Close()
 
-	body, err := ioutil.ReadAll(rawresp.Body)
+	if hasContentType(rawresp, StreamedBatchContentType) {
+		return client.doStreamedBatch(rawresp, batch)
+	}
+
+	body, err := client.readBody(rawresp)
 
 	if err != nil {
 		return err
@@ -77,41 +189,48 @@ func (client *Client) doBatch(req *http.Request, batch *Batch) error {
 
 	var responses []*clientResponse
 
-	err = json.Unmarshal(body, &responses)
+	err = batch.JSON.engine().Unmarshal(body, &responses)
 	if err != nil {
 		return err
 	}
 
 	for _, resp := range responses {
-		result, ok := batch.resultForID(resp.ID)
-		if !ok {
-			if batch.DiscardErrors {
-				continue
-			}
-			err := fmt.Errorf("jsonrpc: unable to find a call with the response ID %s", resp.ID)
+		err = batch.handleResponse(resp)
+		if err != nil {
 			return err
 		}
+	}
+	return nil
+}
 
-		if resp.Error != nil {
-			if batch.DiscardErrors {
-				continue
-			}
-			return resp.Error
+// doStreamedBatch decodes the responses of a streamed batch one at a time,
+// populating each result as soon as its response arrives.
+func (client *Client) doStreamedBatch(rawresp *http.Response, batch *Batch) error {
+	body, err := responseBody(rawresp)
+	if err != nil {
+		return err
+	}
+	decoder := batch.JSON.engine().NewDecoder(body)
+
+	for {
+		var resp clientResponse
+		err := decoder.Decode(&resp)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
 		}
 
-		err = json.Unmarshal(resp.Result, result)
+		err = batch.handleResponse(&resp)
 		if err != nil {
-			if batch.DiscardErrors {
-				continue
-			}
 			return err
 		}
 	}
-	return nil
 }
 
 // Do executes a http.Request and attempts to deserialise the response to the
-// the given result argument.
+// the given result argument. A nil result discards the response's result.
 //
 // While this function will execute any http.Request object, it's a good idea to
 // use the NewRequest function to generate a http.Request in the correct format
@@ -127,6 +246,14 @@ func (client *Client) Batch(url string, batch *Batch) error {
 	if err != nil {
 		return err
 	}
+	err = client.encodeRequest(req)
+	if err != nil {
+		return err
+	}
+	err = client.compressRequest(req)
+	if err != nil {
+		return err
+	}
 	return client.doBatch(req, batch)
 }
 
@@ -138,14 +265,26 @@ func (client *Client) DoBatch(req *http.Request, batch *Batch) error {
 
 // Call makes a single JSONRPC request to the server
 func (client *Client) Call(url string, method string, params interface{}, result interface{}) error {
+	return client.CallContext(context.Background(), url, method, params, result)
+}
 
-	req, err := NewRequest(url, method, params)
+// CallContext makes a single JSONRPC request to the server with the context.
+func (client *Client) CallContext(ctx context.Context, url string, method string, params interface{}, result interface{}) error {
+
+	if client.Cache != nil {
+		ttl, ok := client.CacheTTL[method]
+		if ok {
+			return client.callCached(ctx, url, method, params, result, ttl)
+		}
+	}
+
+	req, err := client.newRequest(url, method, params)
 
 	if err != nil {
 		return err
 	}
 
-	return client.do(req, result)
+	return client.do(req.WithContext(ctx), result)
 }
 
 // NewRequest returns a pointer to a new http.Request containing the call in
@@ -153,6 +292,24 @@ func (client *Client) Call(url string, method string, params interface{}, result
 //
 // The request can then be executed with Client.Do.
 func NewRequest(url string, method string, params interface{}) (*http.Request, error) {
+	return newRequest(url, method, params, nil)
+}
+
+// newRequest returns a request for the call encoded with the client's
+// JSONCodec and Codec.
+func (client *Client) newRequest(url string, method string, params interface{}) (*http.Request, error) {
+	req, err := newRequest(url, method, params, client.JSON)
+	if err != nil {
+		return nil, err
+	}
+	err = client.encodeRequest(req)
+	if err != nil {
+		return nil, err
+	}
+	return req, nil
+}
+
+func newRequest(url string, method string, params interface{}, codec *JSONCodec) (*http.Request, error) {
 
 	call := &clientCall{
 		Version: "2.0",
@@ -161,7 +318,7 @@ func NewRequest(url string, method string, params interface{}) (*http.Request, e
 		Params:  params,
 	}
 
-	data, err := Marshal(call)
+	data, err := codec.Marshal(call)
 
 	if err != nil {
 		return nil, err
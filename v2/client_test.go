@@ -1,6 +1,7 @@
 package jsonrpc
 
 import (
+	"context"
 	"encoding/json"
 	"io/ioutil"
 	"net/http"
@@ -22,7 +23,7 @@ func TestClient_call(t *testing.T) {
 		resp.Result = 6
 	})
 
-	server := httptest.NewServer(&Handler{dispatcher})
+	server := httptest.NewServer(&Handler{Dispatcher: dispatcher})
 	defer server.Close()
 
 	var result int
@@ -41,7 +42,7 @@ func TestClient_with_error(t *testing.T) {
 		}
 	})
 
-	server := httptest.NewServer(&Handler{dispatcher})
+	server := httptest.NewServer(&Handler{Dispatcher: dispatcher})
 	defer server.Close()
 
 	var result int
@@ -72,7 +73,7 @@ func TestClient_with_non_pointer(t *testing.T) {
 		resp.Result = 6
 	})
 
-	server := httptest.NewServer(&Handler{dispatcher})
+	server := httptest.NewServer(&Handler{Dispatcher: dispatcher})
 	defer server.Close()
 
 	var result int
@@ -92,7 +93,7 @@ func TestClient_with_incorrect_result_type(t *testing.T) {
 		resp.Result = 6
 	})
 
-	server := httptest.NewServer(&Handler{dispatcher})
+	server := httptest.NewServer(&Handler{Dispatcher: dispatcher})
 	defer server.Close()
 
 	var result string
@@ -112,7 +113,7 @@ func TestClient_do(t *testing.T) {
 		resp.Result = 6
 	})
 
-	server := httptest.NewServer(&Handler{dispatcher})
+	server := httptest.NewServer(&Handler{Dispatcher: dispatcher})
 	defer server.Close()
 
 	req, err := NewRequest(server.URL, "add", []int{1, 2, 3})
@@ -124,6 +125,45 @@ func TestClient_do(t *testing.T) {
 	assert.Equal(t, result, 6)
 }
 
+func TestClient_without_result(t *testing.T) {
+	client := NewClient()
+	dispatcher := NewMapDispatcher()
+	dispatcher.Register("ping", func(resp *Response, call *Call, req *http.Request) {})
+
+	server := httptest.NewServer(&Handler{Dispatcher: dispatcher})
+	defer server.Close()
+
+	req, err := NewRequest(server.URL, "ping", nil)
+	assert.Nil(t, err)
+
+	err = client.Do(req, nil)
+	assert.Nil(t, err)
+
+	err = client.Call(server.URL, "ping", nil, nil)
+	assert.Nil(t, err)
+}
+
+func TestClient_CallContext(t *testing.T) {
+	client := NewClient()
+	dispatcher := NewMapDispatcher()
+	dispatcher.Register("ping", func(resp *Response, call *Call, req *http.Request) {
+		resp.Result = "pong"
+	})
+
+	server := httptest.NewServer(&Handler{Dispatcher: dispatcher})
+	defer server.Close()
+
+	var result string
+	err := client.CallContext(context.Background(), server.URL, "ping", nil, &result)
+	assert.Nil(t, err)
+	assert.Equal(t, "pong", result)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err = client.CallContext(ctx, server.URL, "ping", nil, &result)
+	assert.NotNil(t, err)
+}
+
 func TestClient_batch(t *testing.T) {
 	client := NewClient()
 	dispatcher := NewMapDispatcher()
@@ -149,7 +189,7 @@ func TestClient_batch(t *testing.T) {
 		resp.Result = result
 	})
 
-	server := httptest.NewServer(&Handler{dispatcher})
+	server := httptest.NewServer(&Handler{Dispatcher: dispatcher})
 	defer server.Close()
 
 	batch := NewBatch()
@@ -180,7 +220,7 @@ func TestClient_batch_ignoring_errors(t *testing.T) {
 		resp.Result = result
 	})
 
-	server := httptest.NewServer(&Handler{dispatcher})
+	server := httptest.NewServer(&Handler{Dispatcher: dispatcher})
 	defer server.Close()
 
 	batch := NewBatch()
@@ -212,7 +252,7 @@ func TestClient_batch_with_error(t *testing.T) {
 		resp.Result = result
 	})
 
-	server := httptest.NewServer(&Handler{dispatcher})
+	server := httptest.NewServer(&Handler{Dispatcher: dispatcher})
 	defer server.Close()
 
 	batch := NewBatch()
@@ -252,7 +292,7 @@ func TestClient_do_batch(t *testing.T) {
 		resp.Result = result
 	})
 
-	server := httptest.NewServer(&Handler{dispatcher})
+	server := httptest.NewServer(&Handler{Dispatcher: dispatcher})
 	defer server.Close()
 
 	batch := NewBatch()
@@ -291,7 +331,7 @@ func TestMethodCall(t *testing.T) {
 		resp.Result = 6
 	})
 
-	server := httptest.NewServer(&Handler{dispatcher})
+	server := httptest.NewServer(&Handler{Dispatcher: dispatcher})
 	defer server.Close()
 
 	var result int
@@ -311,7 +351,7 @@ func TestDo(t *testing.T) {
 		resp.Result = 6
 	})
 
-	server := httptest.NewServer(&Handler{dispatcher})
+	server := httptest.NewServer(&Handler{Dispatcher: dispatcher})
 	defer server.Close()
 
 	req, err := NewRequest(server.URL, "add", []int{1, 2, 3})
@@ -336,7 +376,7 @@ func TestMethodBatch(t *testing.T) {
 		resp.Result = result
 	})
 
-	server := httptest.NewServer(&Handler{dispatcher})
+	server := httptest.NewServer(&Handler{Dispatcher: dispatcher})
 	defer server.Close()
 
 	batch := NewBatch()
@@ -366,7 +406,7 @@ func TestDoBatch(t *testing.T) {
 		resp.Result = result
 	})
 
-	server := httptest.NewServer(&Handler{dispatcher})
+	server := httptest.NewServer(&Handler{Dispatcher: dispatcher})
 	defer server.Close()
 
 	batch := NewBatch()
@@ -384,3 +424,65 @@ func TestDoBatch(t *testing.T) {
 	assert.Equal(t, 15, b)
 	assert.Equal(t, 24, c)
 }
+
+func TestClient_batch_errors_per_call(t *testing.T) {
+	client := NewClient()
+	dispatcher := NewMapDispatcher()
+	dispatcher.Register("add", func(resp *Response, call *Call, req *http.Request) {
+		resp.Result = 1
+	})
+
+	server := httptest.NewServer(&Handler{Dispatcher: dispatcher})
+	defer server.Close()
+
+	batch := NewBatch()
+	batch.DiscardErrors = true
+
+	var a, b int
+	idA := batch.AddCall("add", []int{1}, &a)
+	idB := batch.AddCall("multiply", []int{2}, &b)
+
+	err := client.Batch(server.URL, batch)
+	assert.Nil(t, err)
+	assert.Nil(t, batch.Err(idA))
+	assert.Equal(t, CodeMethodNotFound, batch.Err(idB).(*Error).Code)
+	assert.NotNil(t, batch.Err("99"))
+}
+
+func TestClient_batch_streamed(t *testing.T) {
+	client := NewClient()
+	release := make(chan struct{})
+	dispatcher := NewMapDispatcher()
+	dispatcher.Register("slow", func(resp *Response, call *Call, req *http.Request) {
+		<-release
+		resp.Result = 1
+	})
+	dispatcher.Register("fast", func(resp *Response, call *Call, req *http.Request) {
+		resp.Result = 2
+	})
+
+	server := httptest.NewServer(&Handler{Dispatcher: dispatcher})
+	defer server.Close()
+
+	batch := NewBatch()
+	batch.Stream = true
+
+	var a, b int
+	var order []string
+	idA := batch.AddCall("slow", nil, &a)
+	idB := batch.AddCall("fast", nil, &b)
+	batch.OnResponse = func(id string, err error) {
+		assert.Nil(t, err)
+		order = append(order, id)
+		if id == idB {
+			assert.Equal(t, 2, b)
+			close(release)
+		}
+	}
+
+	err := client.Batch(server.URL, batch)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, a)
+	assert.Equal(t, 2, b)
+	assert.Equal(t, []string{idB, idA}, order)
+}
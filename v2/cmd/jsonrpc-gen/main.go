@@ -0,0 +1,88 @@
+// Command jsonrpc-gen generates a typed Go client, and optionally a server
+// interface, from an OpenRPC document. It is suited to go generate:
+//
+//	//go:generate jsonrpc-gen -package venues -server -out venues_gen.go venues.json
+//
+// The document is read from the file named by the argument, or from standard
+// input when there is none, and the code is written to the -out file or to
+// standard output.
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	jsonrpc "github.com/ingresso-group/gojsonrpc/v2"
+	"github.com/ingresso-group/gojsonrpc/v2/openrpcgen"
+)
+
+func main() {
+	var options openrpcgen.Options
+	flag.StringVar(&options.Package, "package", "", "name of the generated package, the name of the output directory when empty")
+	flag.StringVar(&options.Prefix, "prefix", "", "prefix for the names of the generated Client and Server")
+	flag.BoolVar(&options.Server, "server", false, "also generate a Server interface and RegisterServer")
+	out := flag.String("out", "", "file to write the code to, standard output when empty")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "usage: jsonrpc-gen [flags] [document.json]\n\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	err := run(options, flag.Arg(0), *out)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "jsonrpc-gen: %s\n", err)
+		os.Exit(1)
+	}
+}
+
+func run(options openrpcgen.Options, in string, out string) error {
+	var data []byte
+	var err error
+	if in == "" {
+		data, err = ioutil.ReadAll(os.Stdin)
+	} else {
+		data, err = ioutil.ReadFile(in)
+		options.Source = filepath.Base(in)
+	}
+	if err != nil {
+		return err
+	}
+
+	var document jsonrpc.OpenRPCDocument
+	err = json.Unmarshal(data, &document)
+	if err != nil {
+		return fmt.Errorf("unable to read the OpenRPC document: %s", err)
+	}
+
+	if options.Package == "" {
+		options.Package, err = packageName(out)
+		if err != nil {
+			return err
+		}
+	}
+
+	code, err := openrpcgen.Generate(&document, options)
+	if err != nil {
+		return err
+	}
+
+	if out == "" {
+		_, err = os.Stdout.Write(code)
+		return err
+	}
+	return ioutil.WriteFile(out, code, 0644)
+}
+
+// packageName returns the name of the directory the code is written to, as
+// the default package name.
+func packageName(out string) (string, error) {
+	dir, err := filepath.Abs(filepath.Dir(out))
+	if err != nil {
+		return "", err
+	}
+	return filepath.Base(dir), nil
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ingresso-group/gojsonrpc/v2/openrpcgen"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRun(t *testing.T) {
+	dir, err := ioutil.TempDir("", "jsonrpc-gen")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	out := filepath.Join(dir, "venues", "venues_gen.go")
+	err = os.Mkdir(filepath.Dir(out), 0755)
+	assert.Nil(t, err)
+
+	err = run(openrpcgen.Options{Server: true}, "../../openrpcgen/internal/venues/venues.json", out)
+	assert.Nil(t, err)
+
+	code, err := ioutil.ReadFile(out)
+	assert.Nil(t, err)
+	expected, err := ioutil.ReadFile("../../openrpcgen/internal/venues/venues_gen.go")
+	assert.Nil(t, err)
+	assert.Equal(t, string(expected), string(code))
+}
+
+func TestRunErrors(t *testing.T) {
+	err := run(openrpcgen.Options{Package: "venues"}, "missing.json", "")
+	assert.NotNil(t, err)
+
+	err = run(openrpcgen.Options{Package: "venues"}, "main.go", "")
+	assert.Contains(t, err.Error(), "unable to read the OpenRPC document")
+}
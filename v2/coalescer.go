@@ -0,0 +1,110 @@
+package jsonrpc
+
+import (
+	"sync"
+	"time"
+)
+
+// DefaultCoalesceWindow is the window used by a Coalescer when none is given.
+const DefaultCoalesceWindow = 5 * time.Millisecond
+
+type pendingBatch struct {
+	batch *Batch
+	timer *time.Timer
+	done  chan struct{}
+	err   error
+}
+
+// Coalescer collects calls made to the same URL within a short window of time
+// and sends them to the server as a single Batch, handing each result back to
+// the goroutine that made the call.
+//
+// The window starts when the first call for a URL arrives. The batch is sent
+// when the window closes or when MaxCalls calls have been collected, whichever
+// comes first.
+type Coalescer struct {
+	Client   *Client
+	Window   time.Duration
+	MaxCalls int
+	pending  map[string]*pendingBatch
+	mtx      *sync.Mutex
+}
+
+// NewCoalescer returns a pointer to a Coalescer that sends its batches with
+// the given client. A MaxCalls of zero places no limit on the size of a
+// batch.
+func NewCoalescer(client *Client, window time.Duration, maxCalls int) *Coalescer {
+	coalescer := &Coalescer{
+		Client:   client,
+		Window:   window,
+		MaxCalls: maxCalls,
+		pending:  make(map[string]*pendingBatch),
+		mtx:      new(sync.Mutex),
+	}
+	return coalescer
+}
+
+func (coalescer *Coalescer) client() *Client {
+	if coalescer.Client == nil {
+		return DefaultClient
+	}
+	return coalescer.Client
+}
+
+func (coalescer *Coalescer) window() time.Duration {
+	if coalescer.Window <= 0 {
+		return DefaultCoalesceWindow
+	}
+	return coalescer.Window
+}
+
+// flush sends the pending batch for the url, provided it is still the one
+// waiting to be sent.
+func (coalescer *Coalescer) flush(url string, pending *pendingBatch) {
+	coalescer.mtx.Lock()
+	if coalescer.pending[url] == pending {
+		delete(coalescer.pending, url)
+	}
+	coalescer.mtx.Unlock()
+
+	pending.err = coalescer.client().Batch(url, pending.batch)
+	close(pending.done)
+}
+
+// Call adds the method call to the batch pending for the url and blocks until
+// that batch has been sent and the result deserialised into result.
+func (coalescer *Coalescer) Call(url string, method string, params interface{}, result interface{}) error {
+	coalescer.mtx.Lock()
+
+	pending, ok := coalescer.pending[url]
+	if !ok {
+		batch := NewBatch()
+		batch.DiscardErrors = true
+		pending = &pendingBatch{
+			batch: batch,
+			done:  make(chan struct{}),
+		}
+		coalescer.pending[url] = pending
+		pending.timer = time.AfterFunc(coalescer.window(), func() {
+			coalescer.flush(url, pending)
+		})
+	}
+
+	id := pending.batch.AddCall(method, params, result)
+
+	if coalescer.MaxCalls > 0 && pending.batch.Len() >= coalescer.MaxCalls {
+		delete(coalescer.pending, url)
+		if pending.timer.Stop() {
+			go coalescer.flush(url, pending)
+		}
+	}
+
+	coalescer.mtx.Unlock()
+
+	<-pending.done
+
+	if pending.err != nil {
+		return pending.err
+	}
+	return pending.batch.Err(id)
+}
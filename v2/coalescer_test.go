@@ -0,0 +1,136 @@
+package jsonrpc
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type countingHandler struct {
+	handler  http.Handler
+	requests int32
+}
+
+func (handler *countingHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	atomic.AddInt32(&handler.requests, 1)
+	handler.handler.ServeHTTP(w, r)
+}
+
+func TestCoalescer_Call(t *testing.T) {
+	dispatcher := NewMapDispatcher()
+	dispatcher.Register("double", func(resp *Response, call *Call, req *http.Request) {
+		var params []int
+		json.Unmarshal(call.Params, &params)
+		resp.Result = params[0] * 2
+	})
+
+	handler := &countingHandler{handler: &Handler{Dispatcher: dispatcher}}
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	coalescer := NewCoalescer(NewClient(), 50*time.Millisecond, 0)
+
+	var wg sync.WaitGroup
+	results := make([]int, 10)
+	errs := make([]error, 10)
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			errs[i] = coalescer.Call(server.URL, "double", []int{i}, &results[i])
+		}(i)
+	}
+	wg.Wait()
+
+	for i := 0; i < 10; i++ {
+		assert.Nil(t, errs[i])
+		assert.Equal(t, i*2, results[i])
+	}
+	assert.Equal(t, int32(1), atomic.LoadInt32(&handler.requests))
+}
+
+func TestCoalescer_Call_max_calls(t *testing.T) {
+	dispatcher := NewMapDispatcher()
+	dispatcher.Register("echo", func(resp *Response, call *Call, req *http.Request) {
+		var params []int
+		json.Unmarshal(call.Params, &params)
+		resp.Result = params[0]
+	})
+
+	handler := &countingHandler{handler: &Handler{Dispatcher: dispatcher}}
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	coalescer := NewCoalescer(NewClient(), time.Minute, 2)
+
+	var wg sync.WaitGroup
+	results := make([]int, 4)
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			err := coalescer.Call(server.URL, "echo", []int{i}, &results[i])
+			assert.Nil(t, err)
+		}(i)
+	}
+	wg.Wait()
+
+	assert.Equal(t, []int{0, 1, 2, 3}, results)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&handler.requests))
+}
+
+func TestCoalescer_Call_with_error(t *testing.T) {
+	dispatcher := NewMapDispatcher()
+	dispatcher.Register("echo", func(resp *Response, call *Call, req *http.Request) {
+		var params []int
+		json.Unmarshal(call.Params, &params)
+		resp.Result = params[0]
+	})
+
+	server := httptest.NewServer(&Handler{Dispatcher: dispatcher})
+	defer server.Close()
+
+	coalescer := NewCoalescer(NewClient(), 20*time.Millisecond, 0)
+
+	var wg sync.WaitGroup
+	var a, b int
+	var errA, errB error
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		errA = coalescer.Call(server.URL, "echo", []int{4}, &a)
+	}()
+	go func() {
+		defer wg.Done()
+		errB = coalescer.Call(server.URL, "missing", []int{5}, &b)
+	}()
+	wg.Wait()
+
+	assert.Nil(t, errA)
+	assert.Equal(t, 4, a)
+	if assert.NotNil(t, errB) {
+		assert.Equal(t, CodeMethodNotFound, errB.(*Error).Code)
+	}
+}
+
+func TestCoalescer_Call_missing_response(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ioutil.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`[]`))
+	}))
+	defer server.Close()
+
+	coalescer := NewCoalescer(NewClient(), time.Millisecond, 0)
+
+	var result int
+	err := coalescer.Call(server.URL, "echo", []int{1}, &result)
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,182 @@
+package jsonrpc
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"strconv"
+)
+
+// A Codec encodes request and response bodies in a format other than JSON,
+// such as MessagePack or CBOR.
+//
+// Bodies are transcoded to and from JSON at the edge of the Handler and
+// Client, so methods and results are unaware of the codec in use and
+// Call.UnmarshalParams works the same for every codec. Marshal is only given
+// the generic values produced by decoding JSON: maps with string keys,
+// slices, strings, booleans, nil, int64, uint64 and float64. Unmarshal should
+// decode into an empty interface producing similar values.
+//
+// The round trip through JSON is not free: a batch of calls costs roughly two
+// to three times the time and four to five times the allocations of the same
+// batch sent as JSON, so a codec pays off in bandwidth rather than CPU. It is
+// also lossy for byte strings, which JSON can only hold as base64 strings.
+// They still decode into []byte params, but results reach the client as text
+// strings rather than binary.
+type Codec interface {
+	ContentType() string
+	Marshal(v interface{}) ([]byte, error)
+	Unmarshal(data []byte, v interface{}) error
+}
+
+// ContentType returns the content type of JSON bodies. Implements the Codec
+// interface.
+func (codec *JSONCodec) ContentType() string {
+	return "application/json"
+}
+
+// codecFor returns the codec among codecs whose content type matches the
+// Content-Type header, or nil if there is none.
+func codecFor(codecs []Codec, header http.Header) Codec {
+	contentType, _, err := mime.ParseMediaType(header.Get("Content-Type"))
+	if err != nil {
+		return nil
+	}
+	for _, codec := range codecs {
+		if codec != nil && codec.ContentType() == contentType {
+			return codec
+		}
+	}
+	return nil
+}
+
+// fromJSON converts JSON data to the codec's format.
+func fromJSON(codec Codec, data []byte) ([]byte, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+
+	var v interface{}
+	err := decoder.Decode(&v)
+	if err != nil {
+		return nil, err
+	}
+	return codec.Marshal(genericNumbers(v))
+}
+
+// toJSON converts data in the codec's format to JSON.
+func toJSON(codec Codec, data []byte) ([]byte, error) {
+	var v interface{}
+	err := codec.Unmarshal(data, &v)
+	if err != nil {
+		return nil, err
+	}
+
+	v, err = stringKeys(v)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(v)
+}
+
+// genericNumbers replaces the json.Numbers in a decoded JSON value with
+// int64, uint64 or float64 values so binary codecs encode them natively.
+func genericNumbers(v interface{}) interface{} {
+	switch v := v.(type) {
+	case json.Number:
+		if n, err := v.Int64(); err == nil {
+			return n
+		}
+		if n, err := strconv.ParseUint(string(v), 10, 64); err == nil {
+			return n
+		}
+		n, _ := v.Float64()
+		return n
+	case map[string]interface{}:
+		for key, value := range v {
+			v[key] = genericNumbers(value)
+		}
+	case []interface{}:
+		for i, value := range v {
+			v[i] = genericNumbers(value)
+		}
+	}
+	return v
+}
+
+// stringKeys replaces maps with non-string keys, as some codecs decode maps
+// into, with maps keyed by strings so the value can be encoded as JSON.
+func stringKeys(v interface{}) (interface{}, error) {
+	var err error
+
+	switch v := v.(type) {
+	case map[interface{}]interface{}:
+		converted := make(map[string]interface{}, len(v))
+		for key, value := range v {
+			name, ok := key.(string)
+			if !ok {
+				return nil, fmt.Errorf("jsonrpc: map key %v is not a string", key)
+			}
+			converted[name], err = stringKeys(value)
+			if err != nil {
+				return nil, err
+			}
+		}
+		return converted, nil
+	case map[string]interface{}:
+		for key, value := range v {
+			v[key], err = stringKeys(value)
+			if err != nil {
+				return nil, err
+			}
+		}
+	case []interface{}:
+		for i, value := range v {
+			v[i], err = stringKeys(value)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	return v, nil
+}
+
+// encodeRequest re-encodes the JSON body of the request with the client's
+// Codec, if it has one.
+func (client *Client) encodeRequest(req *http.Request) error {
+	if client.Codec == nil || req.Body == nil {
+		return nil
+	}
+
+	data, err := ioutil.ReadAll(req.Body)
+	req.Body.Close()
+	if err != nil {
+		return err
+	}
+
+	data, err = fromJSON(client.Codec, data)
+	if err != nil {
+		return err
+	}
+
+	setRequestBody(req, data)
+	req.Header.Set("Content-Type", client.Codec.ContentType())
+	return nil
+}
+
+// readBody reads the body of the response as JSON, decoding it with the
+// client's Codec when the response is in its format.
+func (client *Client) readBody(rawresp *http.Response) ([]byte, error) {
+	body, err := readResponseBody(rawresp)
+	if err != nil {
+		return nil, err
+	}
+
+	codec := codecFor([]Codec{client.Codec}, rawresp.Header)
+	if codec == nil {
+		return body, nil
+	}
+	return toJSON(codec, body)
+}
@@ -0,0 +1,69 @@
+package jsonrpc
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// jsonTestCodec is a Codec with its own content type that encodes values as
+// JSON, recording the values it was given.
+type jsonTestCodec struct {
+	marshalled interface{}
+}
+
+func (codec *jsonTestCodec) ContentType() string {
+	return "application/x-test"
+}
+
+func (codec *jsonTestCodec) Marshal(v interface{}) ([]byte, error) {
+	codec.marshalled = v
+	return json.Marshal(v)
+}
+
+func (codec *jsonTestCodec) Unmarshal(data []byte, v interface{}) error {
+	return json.Unmarshal(data, v)
+}
+
+func TestCodecFor(t *testing.T) {
+	codec := &jsonTestCodec{}
+	codecs := []Codec{nil, codec}
+
+	header := http.Header{}
+	assert.Nil(t, codecFor(codecs, header))
+
+	header.Set("Content-Type", "application/x-test; charset=binary")
+	assert.Equal(t, codec, codecFor(codecs, header))
+
+	header.Set("Content-Type", "application/json")
+	assert.Nil(t, codecFor(codecs, header))
+}
+
+func TestFromJSON(t *testing.T) {
+	codec := &jsonTestCodec{}
+
+	_, err := fromJSON(codec, []byte(`{"a": [1, -2, 18446744073709551615, 1.5], "b": {"c": 3}}`))
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"a": []interface{}{int64(1), int64(-2), uint64(18446744073709551615), 1.5},
+		"b": map[string]interface{}{"c": int64(3)},
+	}, codec.marshalled)
+
+	_, err = fromJSON(codec, []byte(`{`))
+	assert.NotNil(t, err)
+}
+
+func TestStringKeys(t *testing.T) {
+	v, err := stringKeys(map[interface{}]interface{}{
+		"a": []interface{}{map[interface{}]interface{}{"b": 1}},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"a": []interface{}{map[string]interface{}{"b": 1}},
+	}, v)
+
+	_, err = stringKeys(map[interface{}]interface{}{1: "a"})
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,216 @@
+package jsonrpc
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// DefaultCompressionThreshold is the size in bytes below which bodies are
+// not worth compressing.
+const DefaultCompressionThreshold = 1024
+
+// DefaultMaxBodySize is the largest request body in bytes, after decoding any
+// content encoding, read by a Handler without a MaxBodySize.
+const DefaultMaxBodySize = 10 << 20
+
+// errBodyTooLarge is returned when a request body is larger than the
+// handler's maximum body size.
+var errBodyTooLarge = errors.New("jsonrpc: request body too large")
+
+const (
+	encodingGzip    = "gzip"
+	encodingDeflate = "deflate"
+)
+
+// decompress wraps body in a reader decoding the content encoding, which may
+// be empty, gzip or deflate.
+func decompress(body io.Reader, encoding string) (io.Reader, error) {
+	switch strings.ToLower(strings.TrimSpace(encoding)) {
+	case "", "identity":
+		return body, nil
+	case encodingGzip, "x-gzip":
+		return gzip.NewReader(body)
+	case encodingDeflate:
+		return zlib.NewReader(body)
+	default:
+		return nil, fmt.Errorf("jsonrpc: unsupported content encoding %s", encoding)
+	}
+}
+
+// compress encodes data with the content encoding, which must be gzip or
+// deflate.
+func compress(data []byte, encoding string) ([]byte, error) {
+	var buf bytes.Buffer
+	var writer io.WriteCloser
+
+	if encoding == encodingDeflate {
+		writer = zlib.NewWriter(&buf)
+	} else {
+		writer = gzip.NewWriter(&buf)
+	}
+
+	_, err := writer.Write(data)
+	if err != nil {
+		return nil, err
+	}
+
+	err = writer.Close()
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// acceptedEncoding returns the encoding to compress a response with based on
+// the request's Accept-Encoding header, preferring gzip, or an empty string
+// when the client accepts neither gzip nor deflate.
+func acceptedEncoding(r *http.Request) string {
+	qualities := make(map[string]float64)
+
+	for _, accept := range strings.Split(r.Header.Get("Accept-Encoding"), ",") {
+		parts := strings.Split(accept, ";")
+		coding := strings.ToLower(strings.TrimSpace(parts[0]))
+		if coding == "" {
+			continue
+		}
+
+		quality := 1.0
+		for _, param := range parts[1:] {
+			param = strings.TrimSpace(param)
+			if strings.HasPrefix(param, "q=") {
+				q, err := strconv.ParseFloat(strings.TrimPrefix(param, "q="), 64)
+				if err == nil {
+					quality = q
+				}
+			}
+		}
+		qualities[coding] = quality
+	}
+
+	best := ""
+	bestQuality := 0.0
+	for _, coding := range []string{encodingGzip, encodingDeflate} {
+		quality, ok := qualities[coding]
+		if !ok {
+			quality, ok = qualities["*"]
+		}
+		if ok && quality > bestQuality {
+			best = coding
+			bestQuality = quality
+		}
+	}
+	return best
+}
+
+// compressionThreshold returns the threshold to use, applying the default for
+// zero.
+func compressionThreshold(threshold int) int {
+	if threshold == 0 {
+		return DefaultCompressionThreshold
+	}
+	return threshold
+}
+
+// readRequestBody reads the body of the request into buf, decoding any
+// content encoding. It fails with errBodyTooLarge once more than max bytes
+// have been decoded, so a small compressed body cannot expand without limit.
+func readRequestBody(r *http.Request, buf *bytes.Buffer, max int64) error {
+	body, err := decompress(r.Body, r.Header.Get("Content-Encoding"))
+	if err != nil {
+		return err
+	}
+	_, err = buf.ReadFrom(io.LimitReader(body, max+1))
+	if err != nil {
+		return err
+	}
+	if int64(buf.Len()) > max {
+		return errBodyTooLarge
+	}
+	return nil
+}
+
+// maxBodySize returns the maximum body size to use, applying the default for
+// zero.
+func maxBodySize(size int64) int64 {
+	if size == 0 {
+		return DefaultMaxBodySize
+	}
+	return size
+}
+
+// writeBody writes a response body, compressing it when the client accepts
+// it and it is large enough to be worth compressing. The Content-Type and any
+// other headers must already be set.
+func (handler *Handler) writeBody(w http.ResponseWriter, r *http.Request, data []byte) {
+	if !handler.DisableCompression {
+		w.Header().Add("Vary", "Accept-Encoding")
+
+		encoding := acceptedEncoding(r)
+		if encoding != "" && len(data) >= compressionThreshold(handler.CompressionThreshold) {
+			compressed, err := compress(data, encoding)
+			if err == nil {
+				w.Header().Set("Content-Encoding", encoding)
+				data = compressed
+			}
+		}
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write(data)
+}
+
+// readResponseBody reads the body of the response, decoding any content
+// encoding the http.Transport has not already removed.
+func readResponseBody(rawresp *http.Response) ([]byte, error) {
+	body, err := responseBody(rawresp)
+	if err != nil {
+		return nil, err
+	}
+	return ioutil.ReadAll(body)
+}
+
+func responseBody(rawresp *http.Response) (io.Reader, error) {
+	return decompress(rawresp.Body, rawresp.Header.Get("Content-Encoding"))
+}
+
+// compressRequest gzips the body of the request when the client is set to
+// compress requests and the body is large enough.
+func (client *Client) compressRequest(req *http.Request) error {
+	if !client.CompressRequests || req.Body == nil || req.Header.Get("Content-Encoding") != "" {
+		return nil
+	}
+
+	data, err := ioutil.ReadAll(req.Body)
+	req.Body.Close()
+	if err != nil {
+		return err
+	}
+
+	if len(data) >= compressionThreshold(client.CompressionThreshold) {
+		data, err = compress(data, encodingGzip)
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Content-Encoding", encodingGzip)
+	}
+
+	setRequestBody(req, data)
+	return nil
+}
+
+// setRequestBody replaces the body of the request.
+func setRequestBody(req *http.Request, data []byte) {
+	req.Body = ioutil.NopCloser(bytes.NewReader(data))
+	req.ContentLength = int64(len(data))
+	req.GetBody = func() (io.ReadCloser, error) {
+		return ioutil.NopCloser(bytes.NewReader(data)), nil
+	}
+}
@@ -0,0 +1,208 @@
+package jsonrpc
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newEchoDispatcher() *MapDispatcher {
+	dispatcher := NewMapDispatcher()
+	dispatcher.Register("echo", func(resp *Response, call *Call, req *http.Request) {
+		var text string
+		call.UnmarshalParams(&text)
+		resp.Result = text
+	})
+	return dispatcher
+}
+
+func TestAcceptedEncoding(t *testing.T) {
+	tests := map[string]string{
+		"":                      "",
+		"gzip":                  "gzip",
+		"deflate":               "deflate",
+		"deflate, gzip":         "gzip",
+		"gzip;q=0.5, deflate":   "deflate",
+		"gzip;q=0":              "",
+		"*":                     "gzip",
+		"br, *;q=0.1":           "gzip",
+		"identity, br":          "",
+		"GZIP ; q=1.0, deflate": "gzip",
+	}
+
+	for header, expected := range tests {
+		r := httptest.NewRequest(http.MethodPost, "/", nil)
+		r.Header.Set("Accept-Encoding", header)
+		assert.Equal(t, expected, acceptedEncoding(r), header)
+	}
+}
+
+func TestServeHTTP_compressed_response(t *testing.T) {
+	handler := &Handler{Dispatcher: newEchoDispatcher()}
+	long := strings.Repeat("a", 2*DefaultCompressionThreshold)
+
+	body := `{"jsonrpc": "2.0", "id": 1, "method": "echo", "params": "` + long + `"}`
+	r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+	r.Header.Set("Accept-Encoding", "gzip")
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+
+	assert.Equal(t, "gzip", w.Header().Get("Content-Encoding"))
+	assert.Equal(t, "Accept-Encoding", w.Header().Get("Vary"))
+
+	reader, err := gzip.NewReader(w.Body)
+	if !assert.Nil(t, err) {
+		t.FailNow()
+	}
+	var result string
+	data, _ := ioutil.ReadAll(reader)
+	assert.Nil(t, decodeResponse(data, &result, nil))
+	assert.Equal(t, long, result)
+
+	// deflate is used when gzip is not accepted
+	r = httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+	r.Header.Set("Accept-Encoding", "deflate")
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+
+	assert.Equal(t, "deflate", w.Header().Get("Content-Encoding"))
+	zreader, err := zlib.NewReader(w.Body)
+	if !assert.Nil(t, err) {
+		t.FailNow()
+	}
+	data, _ = ioutil.ReadAll(zreader)
+	assert.Nil(t, decodeResponse(data, &result, nil))
+	assert.Equal(t, long, result)
+}
+
+func TestServeHTTP_compression_threshold(t *testing.T) {
+	handler := &Handler{Dispatcher: newEchoDispatcher()}
+
+	body := `{"jsonrpc": "2.0", "id": 1, "method": "echo", "params": "short"}`
+	r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+	r.Header.Set("Accept-Encoding", "gzip")
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+
+	assert.Equal(t, "", w.Header().Get("Content-Encoding"))
+	var result string
+	assert.Nil(t, decodeResponse(w.Body.Bytes(), &result, nil))
+	assert.Equal(t, "short", result)
+
+	handler.CompressionThreshold = 1
+	r = httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+	r.Header.Set("Accept-Encoding", "gzip")
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	assert.Equal(t, "gzip", w.Header().Get("Content-Encoding"))
+
+	handler.DisableCompression = true
+	r = httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+	r.Header.Set("Accept-Encoding", "gzip")
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	assert.Equal(t, "", w.Header().Get("Content-Encoding"))
+	assert.Equal(t, "", w.Header().Get("Vary"))
+}
+
+func TestServeHTTP_compressed_request(t *testing.T) {
+	handler := &Handler{Dispatcher: newEchoDispatcher()}
+
+	body, err := compress([]byte(`{"jsonrpc": "2.0", "id": 1, "method": "echo", "params": "zipped"}`), encodingGzip)
+	assert.Nil(t, err)
+
+	r := httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(body))
+	r.Header.Set("Content-Encoding", "gzip")
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+
+	var result string
+	assert.Nil(t, decodeResponse(w.Body.Bytes(), &result, nil))
+	assert.Equal(t, "zipped", result)
+
+	r = httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(body))
+	r.Header.Set("Content-Encoding", "br")
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+
+	err = decodeResponse(w.Body.Bytes(), &result, nil)
+	assert.Equal(t, CodeInvalidRequest, err.(*Error).Code)
+}
+
+func TestServeHTTP_compressed_request_too_large(t *testing.T) {
+	handler := &Handler{Dispatcher: newEchoDispatcher(), MaxBodySize: 1024}
+
+	// a few KB that decode to 10MB
+	bomb, err := compress(bytes.Repeat([]byte(" "), 10<<20), encodingGzip)
+	assert.Nil(t, err)
+
+	r := httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(bomb))
+	r.Header.Set("Content-Encoding", "gzip")
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+
+	assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+	err = decodeResponse(w.Body.Bytes(), nil, nil)
+	assert.Equal(t, CodeParseError, err.(*Error).Code)
+
+	body := `{"jsonrpc": "2.0", "id": 1, "method": "echo", "params": "` + strings.Repeat("a", 1024) + `"}`
+	r = httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+
+	handler.MaxBodySize = 0
+	r = httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestClient_compression(t *testing.T) {
+	var encodings []string
+	handler := &Handler{Dispatcher: newEchoDispatcher(), CompressionThreshold: 1}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		encodings = append(encodings, r.Header.Get("Content-Encoding"))
+		handler.ServeHTTP(w, r)
+	}))
+	defer server.Close()
+
+	// the client asks for deflate itself so the http.Transport leaves the
+	// response for the client to decode
+	client := NewClient()
+	client.CompressRequests = true
+	client.CompressionThreshold = 100
+
+	var a, b string
+	batch := NewBatch()
+	batch.AddCall("echo", "a", &a)
+	batch.AddCall("echo", strings.Repeat("b", 100), &b)
+
+	req, err := batch.NewRequest(server.URL)
+	assert.Nil(t, err)
+	req.Header.Set("Accept-Encoding", "deflate")
+	assert.Nil(t, client.compressRequest(req))
+	assert.Nil(t, client.DoBatch(req, batch))
+	assert.Equal(t, "a", a)
+	assert.Equal(t, strings.Repeat("b", 100), b)
+
+	batch = NewBatch()
+	batch.AddCall("echo", "a", &a)
+	assert.Nil(t, client.Batch(server.URL, batch))
+
+	var c string
+	req, err = NewRequest(server.URL, "echo", "c")
+	assert.Nil(t, err)
+	req.Header.Set("Accept-Encoding", "gzip")
+	assert.Nil(t, client.Do(req, &c))
+	assert.Equal(t, "c", c)
+
+	assert.Equal(t, []string{"gzip", "", ""}, encodings)
+}
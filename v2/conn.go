@@ -0,0 +1,104 @@
+package jsonrpc
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+)
+
+// ErrClosed is returned by calls made on a persistent connection that has
+// been closed.
+var ErrClosed = errors.New("jsonrpc: connection closed")
+
+// messageConn is a persistent connection that exchanges whole JSONRPC
+// messages, each of which holds either a single call or response or a batch
+// of them.
+//
+// WriteMessage must be safe to call from multiple goroutines.
+type messageConn interface {
+	ReadMessage() ([]byte, error)
+	WriteMessage([]byte) error
+	Close() error
+}
+
+// isBatch reports whether the message holds a JSON array.
+func isBatch(data []byte) bool {
+	data = bytes.TrimLeft(data, " \t\r\n")
+	return len(data) > 0 && data[0] == '['
+}
+
+// message holds the union of the fields in a call and a response, allowing
+// messages arriving on a persistent connection to be told apart.
+type message struct {
+	Version string          `json:"jsonrpc"`
+	ID      interface{}     `json:"id"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params"`
+	Result  json.RawMessage `json:"result"`
+	Error   *Error          `json:"error"`
+}
+
+// isCall reports whether the message is a call rather than a response.
+func (msg *message) isCall() bool {
+	return msg.Method != ""
+}
+
+func (msg *message) call() *Call {
+	return &Call{
+		Version: msg.Version,
+		ID:      msg.ID,
+		Method:  msg.Method,
+		Params:  msg.Params,
+	}
+}
+
+func (msg *message) response() *clientResponse {
+	return &clientResponse{
+		Version: msg.Version,
+		ID:      msg.ID,
+		Result:  msg.Result,
+		Error:   msg.Error,
+	}
+}
+
+// parseMessages decodes a message holding a single call or response, or a
+// batch of them.
+func parseMessages(data []byte) (messages []*message, single bool, err error) {
+	if isBatch(data) {
+		err = DefaultJSONEngine.Unmarshal(data, &messages)
+		return messages, false, err
+	}
+
+	var msg *message
+	err = DefaultJSONEngine.Unmarshal(data, &msg)
+	if err != nil {
+		return nil, true, err
+	}
+	return []*message{msg}, true, nil
+}
+
+func writeResponses(conn messageConn, responses []*Response, single bool) error {
+	if len(responses) == 0 {
+		return nil
+	}
+
+	var data []byte
+	var err error
+	if single {
+		data, err = DefaultJSONEngine.Marshal(responses[0])
+	} else {
+		data, err = DefaultJSONEngine.Marshal(responses)
+	}
+
+	if err != nil {
+		data, _ = json.Marshal(&Response{
+			Version: "2.0",
+			Error: &Error{
+				Code:    CodeInternalError,
+				Message: err.Error(),
+			},
+		})
+	}
+
+	return conn.WriteMessage(data)
+}
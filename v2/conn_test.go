@@ -0,0 +1,32 @@
+package jsonrpc
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseMessages(t *testing.T) {
+	messages, single, err := parseMessages([]byte(`{"jsonrpc": "2.0", "id": "1", "method": "add", "params": [1]}`))
+	assert.Nil(t, err)
+	assert.True(t, single)
+	if assert.Len(t, messages, 1) {
+		assert.True(t, messages[0].isCall())
+		assert.Equal(t, "add", messages[0].call().Method)
+	}
+
+	messages, single, err = parseMessages([]byte(` [
+		{"jsonrpc": "2.0", "id": "1", "result": 6},
+		{"jsonrpc": "2.0", "id": "2", "method": "add", "params": [1]}
+	]`))
+	assert.Nil(t, err)
+	assert.False(t, single)
+	if assert.Len(t, messages, 2) {
+		assert.False(t, messages[0].isCall())
+		assert.Equal(t, "6", string(messages[0].response().Result))
+		assert.True(t, messages[1].isCall())
+	}
+
+	_, _, err = parseMessages([]byte(`nope`))
+	assert.NotNil(t, err)
+}
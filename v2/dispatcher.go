@@ -3,6 +3,7 @@ package jsonrpc
 import (
 	"fmt"
 	"net/http"
+	"sort"
 	"sync"
 )
 
@@ -23,21 +24,46 @@ type Dispatcher interface {
 	Dispatch(*Response, *Call, *http.Request)
 }
 
+// A SafeDispatcher is a Dispatcher that knows which of its methods are safe
+// and idempotent, and so may be called with a GET request.
+type SafeDispatcher interface {
+	Dispatcher
+	IsSafe(method string) bool
+}
+
 // Method is the target for the MapDispatcher
 type Method func(*Response, *Call, *http.Request)
 
 // MapDispatcher holds a map of methods and will dispatch based on method name
+//
+// Methods registered with RegisterWithPolicy are only called once the
+// Authorizer has allowed the caller, denied calls are answered with a
+// CodeForbidden error.
+//
+// Methods can be organised into namespaces with Group and Mount, and each
+// MapDispatcher can have its own Middleware added with Use.
 type MapDispatcher struct {
-	methods map[string]Method
-	mtx     *sync.Mutex
+	methods    map[string]Method
+	safe       map[string]bool
+	policies   map[string]*Policy
+	authorizer Authorizer
+	infos      map[string]*MethodInfo
+	mounts     map[string]Dispatcher
+	middleware []Middleware
+	chain      Dispatcher
+	mtx        *sync.RWMutex
 }
 
 // NewMapDispatcher returns a pointer to a MapDispatcher intialised with no
 // methods
 func NewMapDispatcher() *MapDispatcher {
 	dispatcher := &MapDispatcher{
-		methods: make(map[string]Method),
-		mtx:     new(sync.Mutex),
+		methods:  make(map[string]Method),
+		safe:     make(map[string]bool),
+		policies: make(map[string]*Policy),
+		infos:    make(map[string]*MethodInfo),
+		mounts:   make(map[string]Dispatcher),
+		mtx:      new(sync.RWMutex),
 	}
 	return dispatcher
 }
@@ -57,12 +83,123 @@ func (dispatcher *MapDispatcher) Register(name string, method Method) error {
 	return nil
 }
 
+// RegisterSafe registers a method that is safe and idempotent, allowing it to
+// be called with a GET request as well as a POST.
+func (dispatcher *MapDispatcher) RegisterSafe(name string, method Method) error {
+	err := dispatcher.Register(name, method)
+	if err != nil {
+		return err
+	}
+
+	dispatcher.mtx.Lock()
+	defer dispatcher.mtx.Unlock()
+
+	dispatcher.safe[name] = true
+	return nil
+}
+
+// Unregister removes a method from the dispatcher, along with its policy, info
+// and whether it is safe, so later calls to it get a CodeMethodNotFound error.
+// Calls already being handled are not affected.
+func (dispatcher *MapDispatcher) Unregister(name string) error {
+	dispatcher.mtx.Lock()
+	defer dispatcher.mtx.Unlock()
+
+	_, ok := dispatcher.methods[name]
+	if !ok {
+		return fmt.Errorf("jsonrpc: unable to unregister method with name %s as it does not exist", name)
+	}
+
+	delete(dispatcher.methods, name)
+	delete(dispatcher.safe, name)
+	delete(dispatcher.policies, name)
+	delete(dispatcher.infos, name)
+	return nil
+}
+
+// Replace swaps the method registered with the name for another, keeping its
+// policy, info and whether it is safe, or registers it if there is no method
+// with the name. Calls already being handled finish with the old method.
+func (dispatcher *MapDispatcher) Replace(name string, method Method) {
+	dispatcher.mtx.Lock()
+	defer dispatcher.mtx.Unlock()
+
+	dispatcher.methods[name] = method
+}
+
+// Methods returns the sorted names of the registered methods, including the
+// methods of groups and mounted dispatchers that can list theirs.
+func (dispatcher *MapDispatcher) Methods() []string {
+	dispatcher.mtx.RLock()
+	names := make([]string, 0, len(dispatcher.methods))
+	for name := range dispatcher.methods {
+		names = append(names, name)
+	}
+	mounts := make(map[string]Dispatcher, len(dispatcher.mounts))
+	for prefix, mounted := range dispatcher.mounts {
+		mounts[prefix] = mounted
+	}
+	dispatcher.mtx.RUnlock()
+
+	for prefix, mounted := range mounts {
+		lister, ok := mounted.(methodLister)
+		if !ok {
+			continue
+		}
+		for _, name := range lister.Methods() {
+			names = append(names, prefix+"."+name)
+		}
+	}
+
+	sort.Strings(names)
+	return names
+}
+
+// methodLister is implemented by dispatchers that can list their methods.
+type methodLister interface {
+	Methods() []string
+}
+
+// IsSafe reports whether the method was registered with RegisterSafe, or is
+// safe in the dispatcher it is mounted from. Implements the SafeDispatcher
+// interface.
+func (dispatcher *MapDispatcher) IsSafe(method string) bool {
+	dispatcher.mtx.RLock()
+	safe := dispatcher.safe[method]
+	_, registered := dispatcher.methods[method]
+	dispatcher.mtx.RUnlock()
+
+	if safe || registered {
+		return safe
+	}
+
+	mounted, call := dispatcher.mounted(&Call{Method: method})
+	if mounted == nil {
+		return false
+	}
+	safeDispatcher, ok := mounted.(SafeDispatcher)
+	return ok && safeDispatcher.IsSafe(call.Method)
+}
+
 // Dispatch looks for the methods with the given name in the methods map and
-// if found calls it with the original parameters.
+// if found calls it with the original parameters, otherwise passing the call
+// on to a mounted dispatcher.
 //
 // When the method is not found, it returns an error.
 func (dispatcher *MapDispatcher) Dispatch(resp *Response, call *Call, req *http.Request) {
+	dispatcher.mtx.RLock()
+	chain := dispatcher.chain
+	dispatcher.mtx.RUnlock()
 
+	if chain != nil {
+		chain.Dispatch(resp, call, req)
+		return
+	}
+	dispatcher.dispatch(resp, call, req)
+}
+
+// dispatch calls the method without the dispatcher's middleware.
+func (dispatcher *MapDispatcher) dispatch(resp *Response, call *Call, req *http.Request) {
 	if call.Method == "" {
 		resp.Error = &Error{
 			Code:    CodeInvalidRequest,
@@ -71,8 +208,22 @@ func (dispatcher *MapDispatcher) Dispatch(resp *Response, call *Call, req *http.
 		return
 	}
 
+	dispatcher.mtx.RLock()
 	method, ok := dispatcher.methods[call.Method]
+	dispatcher.mtx.RUnlock()
+
 	if !ok {
+		mounted, inner := dispatcher.mounted(call)
+		if mounted != nil {
+			mounted.Dispatch(resp, inner, req)
+			return
+		}
+		if call.Method == UnsubscribeMethod && PeerFromRequest(req) != nil {
+			// subscriptions registered in groups and mounted dispatchers are
+			// ended without their prefix
+			unsubscribe(resp, call, req)
+			return
+		}
 		resp.Error = &Error{
 			Code:    CodeMethodNotFound,
 			Message: fmt.Sprintf("jsonrpc: method with name %s not registered", call.Method),
@@ -80,6 +231,12 @@ func (dispatcher *MapDispatcher) Dispatch(resp *Response, call *Call, req *http.
 		return
 	}
 
+	denied := dispatcher.authorize(call, req)
+	if denied != nil {
+		resp.Error = denied
+		return
+	}
+
 	method(resp, call, req)
 }
 
@@ -89,6 +246,26 @@ func Register(name string, method Method) error {
 	return err
 }
 
+// Unregister removes the method from the DefaultDispatcher
+func Unregister(name string) error {
+	return DefaultDispatcher.Unregister(name)
+}
+
+// Replace swaps the method registered with the DefaultDispatcher
+func Replace(name string, method Method) {
+	DefaultDispatcher.Replace(name, method)
+}
+
+// Methods lists the methods of the DefaultDispatcher
+func Methods() []string {
+	return DefaultDispatcher.Methods()
+}
+
+// RegisterSafe adds the safe method to the DefaultDispatcher
+func RegisterSafe(name string, method Method) error {
+	return DefaultDispatcher.RegisterSafe(name, method)
+}
+
 // Dispatch a call with the DefaultDispatcher
 func Dispatch(resp *Response, call *Call, req *http.Request) {
 	DefaultDispatcher.Dispatch(resp, call, req)
@@ -3,11 +3,63 @@ package jsonrpc
 import (
 	"encoding/json"
 	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
 )
 
+// nameMethod answers with the name of the method called.
+func nameMethod(resp *Response, call *Call, req *http.Request) {
+	resp.Result = call.Method
+}
+
+// countCalls wraps the method, counting its calls.
+func countCalls(calls *int32, method Method) Method {
+	return func(resp *Response, call *Call, req *http.Request) {
+		atomic.AddInt32(calls, 1)
+		method(resp, call, req)
+	}
+}
+
+// newNameDispatcher returns a MapDispatcher with the methods registered as
+// nameMethod, counting their calls.
+func newNameDispatcher(calls *int32, methods ...string) *MapDispatcher {
+	dispatcher := NewMapDispatcher()
+	for _, method := range methods {
+		dispatcher.Register(method, countCalls(calls, nameMethod))
+	}
+	return dispatcher
+}
+
+// requestAs returns a request made by the principal, or by an unauthenticated
+// caller when it is nil.
+func requestAs(principal *Principal) *http.Request {
+	req := httptest.NewRequest(http.MethodPost, "/", nil)
+	if principal != nil {
+		req = req.WithContext(WithPrincipal(req.Context(), principal))
+	}
+	return req
+}
+
+// dispatchCall dispatches a call to the method with the raw JSON params,
+// omitted when empty, and returns its response. A nil req is replaced by a
+// plain POST request.
+func dispatchCall(dispatcher Dispatcher, req *http.Request, method string, params string) *Response {
+	if req == nil {
+		req = httptest.NewRequest(http.MethodPost, "/", nil)
+	}
+
+	call := &Call{Version: "2.0", ID: "1", Method: method}
+	if params != "" {
+		call.Params = json.RawMessage(params)
+	}
+	resp := NewResponse(call)
+	dispatcher.Dispatch(resp, call, req)
+	return resp
+}
+
 func TestMapDispatcher(t *testing.T) {
 	dispatcher := NewMapDispatcher()
 	dispatcher.Register("add", func(resp *Response, call *Call, req *http.Request) {
@@ -115,3 +167,86 @@ func TestDispatch(t *testing.T) {
 	Dispatch(resp, call, nil)
 	assert.Equal(t, "hello world", resp.Result)
 }
+
+func TestMapDispatcher_RegisterSafe(t *testing.T) {
+	dispatcher := NewMapDispatcher()
+	dispatcher.Register("book", func(resp *Response, call *Call, req *http.Request) {})
+	err := dispatcher.RegisterSafe("venue", func(resp *Response, call *Call, req *http.Request) {})
+
+	assert.Nil(t, err)
+	assert.True(t, dispatcher.IsSafe("venue"))
+	assert.False(t, dispatcher.IsSafe("book"))
+	assert.False(t, dispatcher.IsSafe("missing"))
+
+	err = dispatcher.RegisterSafe("book", func(resp *Response, call *Call, req *http.Request) {})
+	assert.NotNil(t, err)
+	assert.False(t, dispatcher.IsSafe("book"))
+}
+
+func TestMapDispatcher_Unregister(t *testing.T) {
+	dispatcher := NewMapDispatcher()
+	dispatcher.RegisterSafe("venue", nameMethod)
+	dispatcher.RegisterWithPolicy("refund", nameMethod, &Policy{Roles: []string{"admin"}})
+
+	assert.Nil(t, dispatcher.Unregister("venue"))
+	assert.Nil(t, dispatcher.Unregister("refund"))
+	assert.NotNil(t, dispatcher.Unregister("venue"))
+
+	assert.Equal(t, CodeMethodNotFound, dispatchCall(dispatcher, nil, "venue", "").Error.Code)
+	assert.False(t, dispatcher.IsSafe("venue"))
+
+	// the name can be registered again without the old policy
+	assert.Nil(t, dispatcher.Register("refund", nameMethod))
+	assert.Nil(t, dispatchCall(dispatcher, nil, "refund", "").Error)
+}
+
+func TestMapDispatcher_Replace(t *testing.T) {
+	dispatcher := NewMapDispatcher()
+	dispatcher.RegisterSafe("venue", nameMethod)
+
+	dispatcher.Replace("venue", func(resp *Response, call *Call, req *http.Request) {
+		resp.Result = "new venue"
+	})
+	assert.Equal(t, "new venue", dispatchCall(dispatcher, nil, "venue", "").Result)
+	assert.True(t, dispatcher.IsSafe("venue"))
+
+	dispatcher.Replace("book", nameMethod)
+	assert.Equal(t, "book", dispatchCall(dispatcher, nil, "book", "").Result)
+}
+
+func TestMapDispatcher_Replace_concurrent(t *testing.T) {
+	dispatcher := NewMapDispatcher()
+	dispatcher.Register("flag", nameMethod)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 100; i++ {
+			dispatcher.Replace("flag", nameMethod)
+			dispatcher.Unregister("other")
+			dispatcher.Register("other", nameMethod)
+		}
+	}()
+
+	for i := 0; i < 100; i++ {
+		assert.Equal(t, "flag", dispatchCall(dispatcher, nil, "flag", "").Result)
+		dispatcher.Methods()
+	}
+	<-done
+}
+
+func TestMapDispatcher_Methods(t *testing.T) {
+	dispatcher := NewMapDispatcher()
+	dispatcher.Register("ping", nameMethod)
+	dispatcher.Register("add", nameMethod)
+	dispatcher.Group("venue").Register("get", nameMethod)
+	dispatcher.Group("venue").Group("seats").Register("list", nameMethod)
+	dispatcher.Mount("legacy", Method(nameMethod))
+
+	cached := NewCachingDispatcher(NewMapDispatcher(), 10)
+	cached.Dispatcher.(*MapDispatcher).Register("search", nameMethod)
+	dispatcher.Mount("search", cached)
+
+	assert.Equal(t, []string{"add", "ping", "search.search", "venue.get", "venue.seats.list"}, dispatcher.Methods())
+	assert.Equal(t, []string{}, NewMapDispatcher().Methods())
+}
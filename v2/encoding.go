@@ -0,0 +1,132 @@
+package jsonrpc
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+)
+
+// A JSONEngine encodes and decodes JSON with the same behaviour as
+// encoding/json, allowing a faster implementation such as jsoniter to be used
+// in its place.
+//
+// As with encoding/json, Unmarshal must not retain the data it is given, as
+// request bodies are read into pooled buffers.
+type JSONEngine interface {
+	Marshal(v interface{}) ([]byte, error)
+	Unmarshal(data []byte, v interface{}) error
+	NewEncoder(w io.Writer) JSONEncoder
+	NewDecoder(r io.Reader) JSONDecoder
+}
+
+// JSONEncoder is the part of json.Encoder used by a JSONEngine.
+type JSONEncoder interface {
+	Encode(v interface{}) error
+	SetIndent(prefix string, indent string)
+	SetEscapeHTML(on bool)
+}
+
+// JSONDecoder is the part of json.Decoder used by a JSONEngine.
+type JSONDecoder interface {
+	Decode(v interface{}) error
+	UseNumber()
+}
+
+type standardJSONEngine struct{}
+
+func (standardJSONEngine) Marshal(v interface{}) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+func (standardJSONEngine) Unmarshal(data []byte, v interface{}) error {
+	return json.Unmarshal(data, v)
+}
+
+func (standardJSONEngine) NewEncoder(w io.Writer) JSONEncoder {
+	return json.NewEncoder(w)
+}
+
+func (standardJSONEngine) NewDecoder(r io.Reader) JSONDecoder {
+	return json.NewDecoder(r)
+}
+
+var (
+	// StandardJSONEngine is the JSONEngine backed by encoding/json.
+	StandardJSONEngine JSONEngine = standardJSONEngine{}
+	// DefaultJSONEngine is used by Call.UnmarshalParams, persistent
+	// connections and every JSONCodec without its own Engine. It should only
+	// be replaced before serving or making calls.
+	DefaultJSONEngine = StandardJSONEngine
+)
+
+// JSONCodec controls how a Handler, Client or Batch encodes and decodes
+// JSON, letting each choose its own settings without touching the package
+// level IndentOutput and EscapeHTML.
+//
+// A nil *JSONCodec encodes according to IndentOutput and EscapeHTML using
+// the DefaultJSONEngine.
+type JSONCodec struct {
+	// Indent is repeated once for each level of nesting, no indentation is
+	// applied when it is empty.
+	Indent string
+	// EscapeHTML escapes <, > and & in strings.
+	EscapeHTML bool
+	// UseNumber decodes numbers into interface{} values as json.Number
+	// rather than float64.
+	UseNumber bool
+	// Engine encodes and decodes the JSON, DefaultJSONEngine is used when it
+	// is nil.
+	Engine JSONEngine
+}
+
+// defaultJSONCodec returns a JSONCodec following the package level settings.
+func defaultJSONCodec() *JSONCodec {
+	codec := &JSONCodec{
+		EscapeHTML: EscapeHTML,
+	}
+	if IndentOutput {
+		codec.Indent = "  "
+	}
+	return codec
+}
+
+// Marshal returns the JSON encoding of v.
+func (codec *JSONCodec) Marshal(v interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	err := codec.encode(&buf, v)
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// encode writes the JSON encoding of v to w.
+func (codec *JSONCodec) encode(w io.Writer, v interface{}) error {
+	if codec == nil {
+		codec = defaultJSONCodec()
+	}
+
+	encoder := codec.engine().NewEncoder(w)
+	encoder.SetIndent("", codec.Indent)
+	encoder.SetEscapeHTML(codec.EscapeHTML)
+	return encoder.Encode(v)
+}
+
+// Unmarshal decodes the JSON data into v.
+func (codec *JSONCodec) Unmarshal(data []byte, v interface{}) error {
+	if codec == nil || !codec.UseNumber {
+		return codec.engine().Unmarshal(data, v)
+	}
+
+	decoder := codec.engine().NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	return decoder.Decode(v)
+}
+
+// engine returns the JSONEngine used by the codec.
+func (codec *JSONCodec) engine() JSONEngine {
+	if codec == nil || codec.Engine == nil {
+		return DefaultJSONEngine
+	}
+	return codec.Engine
+}
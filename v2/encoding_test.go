@@ -0,0 +1,117 @@
+package jsonrpc
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJSONCodec_Marshal(t *testing.T) {
+	value := map[string]string{"a": "<b>"}
+
+	data, err := (&JSONCodec{}).Marshal(value)
+	assert.Nil(t, err)
+	assert.Equal(t, "{\"a\":\"<b>\"}\n", string(data))
+
+	data, err = (&JSONCodec{Indent: "\t", EscapeHTML: true}).Marshal(value)
+	assert.Nil(t, err)
+	assert.Equal(t, "{\n\t\"a\": \"\\u003cb\\u003e\"\n}\n", string(data))
+}
+
+func TestJSONCodec_Marshal_nil(t *testing.T) {
+	defer func(indent bool, escape bool) {
+		IndentOutput = indent
+		EscapeHTML = escape
+	}(IndentOutput, EscapeHTML)
+
+	var codec *JSONCodec
+	value := map[string]string{"a": "<b>"}
+
+	IndentOutput = false
+	EscapeHTML = false
+	data, err := codec.Marshal(value)
+	assert.Nil(t, err)
+	assert.Equal(t, "{\"a\":\"<b>\"}\n", string(data))
+
+	EscapeHTML = true
+	data, err = Marshal(value)
+	assert.Nil(t, err)
+	assert.Equal(t, "{\"a\":\"\\u003cb\\u003e\"}\n", string(data))
+}
+
+func TestMarshal_default(t *testing.T) {
+	data, err := Marshal("<a>&")
+	assert.Nil(t, err)
+	assert.Equal(t, "\"\\u003ca\\u003e\\u0026\"\n", string(data))
+}
+
+func TestJSONCodec_Unmarshal(t *testing.T) {
+	var v interface{}
+
+	assert.Nil(t, (*JSONCodec)(nil).Unmarshal([]byte(`12345678901234567890`), &v))
+	assert.IsType(t, float64(0), v)
+
+	assert.Nil(t, (&JSONCodec{UseNumber: true}).Unmarshal([]byte(`12345678901234567890`), &v))
+	assert.Equal(t, json.Number("12345678901234567890"), v)
+}
+
+func TestServeHTTP_JSON(t *testing.T) {
+	dispatcher := NewMapDispatcher()
+	dispatcher.Register("markup", func(resp *Response, call *Call, req *http.Request) {
+		resp.Result = "<b>"
+	})
+
+	handler := &Handler{
+		Dispatcher: dispatcher,
+		JSON:       &JSONCodec{EscapeHTML: true},
+	}
+
+	body := `{"jsonrpc": "2.0", "id": 1, "method": "markup"}`
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body)))
+
+	assert.Equal(t, "{\"jsonrpc\":\"2.0\",\"id\":1,\"result\":\"\\u003cb\\u003e\"}\n", w.Body.String())
+}
+
+func TestClient_JSON(t *testing.T) {
+	dispatcher := NewMapDispatcher()
+	dispatcher.Register("big", func(resp *Response, call *Call, req *http.Request) {
+		resp.Result = json.RawMessage(`{"id": 12345678901234567890}`)
+	})
+
+	server := httptest.NewServer(&Handler{Dispatcher: dispatcher})
+	defer server.Close()
+
+	client := NewClient()
+	client.JSON = &JSONCodec{UseNumber: true}
+
+	var result map[string]interface{}
+	err := client.Call(server.URL, "big", nil, &result)
+	assert.Nil(t, err)
+	assert.Equal(t, json.Number("12345678901234567890"), result["id"])
+
+	var batchResult map[string]interface{}
+	batch := NewBatch()
+	batch.JSON = &JSONCodec{UseNumber: true}
+	batch.AddCall("big", nil, &batchResult)
+	assert.Nil(t, client.Batch(server.URL, batch))
+	assert.Equal(t, json.Number("12345678901234567890"), batchResult["id"])
+}
+
+func TestBatch_NewRequest_JSON(t *testing.T) {
+	batch := NewBatch()
+	batch.JSON = &JSONCodec{}
+	batch.AddCall("add", []int{1, 2}, nil)
+
+	req, err := batch.NewRequest("http://localhost")
+	assert.Nil(t, err)
+
+	body, err := ioutil.ReadAll(req.Body)
+	assert.Nil(t, err)
+	assert.Equal(t, "[{\"jsonrpc\":\"2.0\",\"id\":\"1\",\"method\":\"add\",\"params\":[1,2]}]\n", string(body))
+}
@@ -0,0 +1,153 @@
+package jsonrpc
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// decodeQueryParams decodes the params of a GET request, sent either as url
+// encoded JSON in params or as base64url encoded JSON in params64. Each key
+// has a single encoding, so a value is never guessed at.
+func decodeQueryParams(query url.Values) (json.RawMessage, error) {
+	value, encoded := query.Get("params"), query.Get("params64")
+	if value != "" && encoded != "" {
+		return nil, fmt.Errorf("jsonrpc: params and params64 cannot both be sent")
+	}
+
+	if encoded != "" {
+		data, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(encoded, "="))
+		if err != nil || !json.Valid(data) {
+			return nil, fmt.Errorf("jsonrpc: params64 must be base64url encoded JSON")
+		}
+		return json.RawMessage(data), nil
+	}
+
+	if value == "" {
+		return nil, nil
+	}
+	if !json.Valid([]byte(value)) {
+		return nil, fmt.Errorf("jsonrpc: params must be url encoded JSON")
+	}
+	return json.RawMessage(value), nil
+}
+
+// cacheHeaders are the response headers that let a GET response be cached,
+// which are only sent with successful results.
+var cacheHeaders = map[string]bool{
+	"Cache-Control": true,
+	"Etag":          true,
+	"Expires":       true,
+	"Last-Modified": true,
+}
+
+// serveGet handles a call to a safe method made with a GET request of the form
+// ?method=...&params=...&id=... or ?method=...&params64=...&id=...
+//
+// Any headers set on the Response by the method are sent to the client,
+// except for caching headers when the call failed, and when the method sets
+// an ETag matching the request's If-None-Match header the response body is
+// omitted.
+func (handler *Handler) serveGet(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	method := query.Get("method")
+
+	safe, ok := handler.Dispatcher.(SafeDispatcher)
+	if !ok || !safe.IsSafe(method) {
+		handler.serverError(w, fmt.Sprintf("jsonrpc: method %s cannot be called with a GET request", method), CodeInvalidRequest)
+		return
+	}
+
+	params, err := decodeQueryParams(query)
+	if err != nil {
+		handler.serverError(w, err.Error(), CodeParseError)
+		return
+	}
+
+	call := &Call{
+		Version: "2.0",
+		Method:  method,
+		Params:  params,
+	}
+	if id := query.Get("id"); id != "" {
+		call.ID = id
+	}
+
+	resp := NewResponse(call)
+	handler.Dispatcher.Dispatch(resp, call, r)
+
+	data, err := handler.JSON.Marshal(resp)
+	if err != nil {
+		handler.serverError(w, err.Error(), CodeInternalError)
+		return
+	}
+
+	for name, values := range resp.Header {
+		// errors must not be cached by proxies
+		if resp.Error != nil && cacheHeaders[http.CanonicalHeaderKey(name)] {
+			continue
+		}
+		for _, value := range values {
+			w.Header().Add(name, value)
+		}
+	}
+
+	etag := resp.Header.Get("ETag")
+	if etag != "" && resp.Error == nil && etagMatches(r.Header.Get("If-None-Match"), etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	handler.writeBody(w, r, data)
+}
+
+// etagMatches reports whether the If-None-Match header matches the ETag.
+func etagMatches(ifNoneMatch string, etag string) bool {
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == strings.TrimPrefix(etag, "W/") {
+			return true
+		}
+	}
+	return false
+}
+
+// NewGetRequest returns a pointer to a new http.Request calling a safe method
+// with a GET request, which can be cached by proxies. The params are sent as
+// base64url encoded JSON in params64.
+//
+// The request can then be executed with Client.Do.
+func NewGetRequest(rawurl string, method string, params interface{}) (*http.Request, error) {
+	query := url.Values{}
+	query.Set("method", method)
+	query.Set("id", "1")
+
+	if params != nil {
+		data, err := json.Marshal(params)
+		if err != nil {
+			return nil, err
+		}
+		query.Set("params64", base64.RawURLEncoding.EncodeToString(data))
+	}
+
+	target, err := url.Parse(rawurl)
+	if err != nil {
+		return nil, err
+	}
+	target.RawQuery = query.Encode()
+
+	return http.NewRequest(http.MethodGet, target.String(), nil)
+}
+
+// Get calls a safe method on the server with a GET request.
+func (client *Client) Get(url string, method string, params interface{}, result interface{}) error {
+	req, err := NewGetRequest(url, method, params)
+	if err != nil {
+		return err
+	}
+	return client.do(req, result)
+}
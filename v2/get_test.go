@@ -0,0 +1,168 @@
+package jsonrpc
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newVenueDispatcher() *MapDispatcher {
+	dispatcher := NewMapDispatcher()
+	dispatcher.RegisterSafe("venue", func(resp *Response, call *Call, req *http.Request) {
+		var params struct {
+			ID int `json:"id"`
+		}
+		call.UnmarshalParams(&params)
+		resp.Header = http.Header{}
+		resp.Header.Set("Cache-Control", "max-age=60")
+		resp.Header.Set("ETag", `"venue-1"`)
+		resp.Result = params.ID
+	})
+	dispatcher.Register("book", func(resp *Response, call *Call, req *http.Request) {
+		resp.Result = true
+	})
+	return dispatcher
+}
+
+func TestDecodeQueryParams(t *testing.T) {
+	params, err := decodeQueryParams(url.Values{"params": {`{"id": 1}`}})
+	assert.Nil(t, err)
+	assert.Equal(t, `{"id": 1}`, string(params))
+
+	params, err = decodeQueryParams(url.Values{"params64": {`eyJpZCI6IDF9`}})
+	assert.Nil(t, err)
+	assert.Equal(t, `{"id": 1}`, string(params))
+
+	params, err = decodeQueryParams(url.Values{"params64": {`WzFd`}})
+	assert.Nil(t, err)
+	assert.Equal(t, `[1]`, string(params))
+
+	// padding is optional
+	params, err = decodeQueryParams(url.Values{"params64": {`WzEwXQ==`}})
+	assert.Nil(t, err)
+	assert.Equal(t, `[10]`, string(params))
+
+	params, err = decodeQueryParams(url.Values{})
+	assert.Nil(t, err)
+	assert.Nil(t, params)
+
+	// base64 is never guessed from params, nor JSON from params64
+	_, err = decodeQueryParams(url.Values{"params": {`WzFd`}})
+	assert.NotNil(t, err)
+	_, err = decodeQueryParams(url.Values{"params64": {`[1]`}})
+	assert.NotNil(t, err)
+
+	// a value that is both valid JSON and valid base64 keeps its key's meaning
+	params, err = decodeQueryParams(url.Values{"params": {`1234`}})
+	assert.Nil(t, err)
+	assert.Equal(t, `1234`, string(params))
+	_, err = decodeQueryParams(url.Values{"params64": {`1234`}})
+	assert.NotNil(t, err)
+
+	_, err = decodeQueryParams(url.Values{"params": {`[1]`}, "params64": {`WzFd`}})
+	assert.NotNil(t, err)
+
+	_, err = decodeQueryParams(url.Values{"params": {`nope!`}})
+	assert.NotNil(t, err)
+}
+
+func TestServeHTTP_get(t *testing.T) {
+	server := httptest.NewServer(&Handler{Dispatcher: newVenueDispatcher()})
+	defer server.Close()
+
+	query := url.Values{}
+	query.Set("method", "venue")
+	query.Set("params", `{"id": 12}`)
+	query.Set("id", "abc")
+
+	response, err := http.Get(server.URL + "?" + query.Encode())
+	if !assert.Nil(t, err) {
+		t.FailNow()
+	}
+
+	assert.Equal(t, http.StatusOK, response.StatusCode)
+	assert.Equal(t, "application/json", response.Header.Get("Content-Type"))
+	assert.Equal(t, "max-age=60", response.Header.Get("Cache-Control"))
+	assert.Equal(t, `"venue-1"`, response.Header.Get("ETag"))
+
+	body, _ := ioutil.ReadAll(response.Body)
+	var result Response
+	json.Unmarshal(body, &result)
+	assert.Equal(t, "abc", result.ID)
+	assert.Equal(t, 12.0, result.Result)
+}
+
+func TestServeHTTP_get_error_not_cached(t *testing.T) {
+	dispatcher := NewMapDispatcher()
+	dispatcher.RegisterSafe("venue", func(resp *Response, call *Call, req *http.Request) {
+		resp.Header = http.Header{}
+		resp.Header.Set("Cache-Control", "max-age=60")
+		resp.Header.Set("ETag", `"venue-1"`)
+		resp.Header.Set("Retry-After", "5")
+		resp.Error = &Error{Code: CodeInvalidParameters, Message: "no such venue"}
+	})
+
+	r := httptest.NewRequest(http.MethodGet, "/?method=venue&id=1", nil)
+	r.Header.Set("If-None-Match", `"venue-1"`)
+	w := httptest.NewRecorder()
+	(&Handler{Dispatcher: dispatcher}).ServeHTTP(w, r)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "", w.Header().Get("Cache-Control"))
+	assert.Equal(t, "", w.Header().Get("ETag"))
+	assert.Equal(t, "5", w.Header().Get("Retry-After"))
+	assert.Contains(t, w.Body.String(), "no such venue")
+}
+
+func TestServeHTTP_get_not_modified(t *testing.T) {
+	server := httptest.NewServer(&Handler{Dispatcher: newVenueDispatcher()})
+	defer server.Close()
+
+	req, _ := NewGetRequest(server.URL, "venue", map[string]int{"id": 12})
+	req.Header.Set("If-None-Match", `"venue-1"`)
+
+	response, err := http.DefaultClient.Do(req)
+	if !assert.Nil(t, err) {
+		t.FailNow()
+	}
+	assert.Equal(t, http.StatusNotModified, response.StatusCode)
+}
+
+func TestServeHTTP_get_unsafe_method(t *testing.T) {
+	server := httptest.NewServer(&Handler{Dispatcher: newVenueDispatcher()})
+	defer server.Close()
+
+	response, err := http.Get(server.URL + "?method=book")
+	if !assert.Nil(t, err) {
+		t.FailNow()
+	}
+
+	body, _ := ioutil.ReadAll(response.Body)
+	var result Response
+	json.Unmarshal(body, &result)
+	assert.Equal(t, CodeInvalidRequest, result.Error.Code)
+	assert.Equal(t, "jsonrpc: method book cannot be called with a GET request", result.Error.Message)
+}
+
+func TestClient_Get(t *testing.T) {
+	server := httptest.NewServer(&Handler{Dispatcher: newVenueDispatcher()})
+	defer server.Close()
+
+	var result int
+	err := NewClient().Get(server.URL, "venue", map[string]int{"id": 7}, &result)
+	assert.Nil(t, err)
+	assert.Equal(t, 7, result)
+}
+
+func TestEtagMatches(t *testing.T) {
+	assert.True(t, etagMatches(`"a", "b"`, `"b"`))
+	assert.True(t, etagMatches(`W/"b"`, `"b"`))
+	assert.True(t, etagMatches(`*`, `"b"`))
+	assert.False(t, etagMatches(`"a"`, `"b"`))
+	assert.False(t, etagMatches(``, `"b"`))
+}
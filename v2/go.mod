@@ -4,9 +4,13 @@ go 1.11
 
 require (
 	github.com/certifi/gocertifi v0.0.0-20190415143156-92f724a62f3e // indirect
+	github.com/fxamacker/cbor/v2 v2.4.0
 	github.com/getsentry/raven-go v0.2.0
+	github.com/gorilla/websocket v1.4.2
+	github.com/json-iterator/go v1.1.12
 	github.com/pkg/errors v0.8.1 // indirect
 	github.com/prometheus/client_golang v0.9.2
 	github.com/smartystreets/goconvey v0.0.0-20190330032615-68dc04aab96a
-	github.com/stretchr/testify v1.3.0
+	github.com/stretchr/testify v1.6.1
+	github.com/vmihailenco/msgpack/v5 v5.3.5
 )
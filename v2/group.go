@@ -0,0 +1,116 @@
+package jsonrpc
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// Middleware wraps a Dispatcher, such as with a CachingDispatcher or a
+// RateLimitDispatcher, to change how its calls are handled.
+type Middleware func(Dispatcher) Dispatcher
+
+// Dispatch calls the method. Implements the Dispatcher interface, so a Method
+// can be mounted or wrapped by a Middleware.
+func (method Method) Dispatch(resp *Response, call *Call, req *http.Request) {
+	method(resp, call, req)
+}
+
+// Mount passes calls to methods named with the prefix followed by a dot on to
+// the dispatcher, with the prefix and dot removed from the method name. Methods
+// registered directly take precedence over mounted ones, and the longest
+// matching prefix is used when mounts are nested.
+func (dispatcher *MapDispatcher) Mount(prefix string, mounted Dispatcher) error {
+	dispatcher.mtx.Lock()
+	defer dispatcher.mtx.Unlock()
+
+	if prefix == "" || strings.HasSuffix(prefix, ".") {
+		return fmt.Errorf("jsonrpc: invalid prefix %q", prefix)
+	}
+
+	_, ok := dispatcher.mounts[prefix]
+	if ok {
+		return fmt.Errorf("jsonrpc: unable to mount a dispatcher at %s as one already exists", prefix)
+	}
+
+	dispatcher.mounts[prefix] = mounted
+	return nil
+}
+
+// Group returns a MapDispatcher whose methods are called with the name
+// followed by a dot, so "get" registered with Group("venue") is called as
+// "venue.get". Calling Group again with the same name returns the same
+// MapDispatcher, replacing any other Dispatcher mounted at the name.
+func (dispatcher *MapDispatcher) Group(name string) *MapDispatcher {
+	dispatcher.mtx.Lock()
+	defer dispatcher.mtx.Unlock()
+
+	group, ok := dispatcher.mounts[name].(*MapDispatcher)
+	if !ok {
+		group = NewMapDispatcher()
+		dispatcher.mounts[name] = group
+	}
+	return group
+}
+
+// Use adds middleware wrapping every call to the dispatcher, including calls
+// to its groups and mounted dispatchers. The first middleware added is the
+// outermost.
+func (dispatcher *MapDispatcher) Use(middleware ...Middleware) {
+	dispatcher.mtx.Lock()
+	dispatcher.middleware = append(dispatcher.middleware, middleware...)
+	all := dispatcher.middleware
+	dispatcher.mtx.Unlock()
+
+	var chain Dispatcher = unwrappedDispatcher{dispatcher}
+	for i := len(all) - 1; i >= 0; i-- {
+		chain = all[i](chain)
+	}
+
+	dispatcher.mtx.Lock()
+	defer dispatcher.mtx.Unlock()
+
+	dispatcher.chain = chain
+}
+
+// unwrappedDispatcher is the innermost Dispatcher wrapped by a MapDispatcher's
+// middleware, keeping the methods that let wrappers such as the
+// CachingDispatcher check whether calls are safe and authorized.
+type unwrappedDispatcher struct {
+	*MapDispatcher
+}
+
+// Dispatch calls the method without the middleware.
+func (unwrapped unwrappedDispatcher) Dispatch(resp *Response, call *Call, req *http.Request) {
+	unwrapped.dispatch(resp, call, req)
+}
+
+// mounted returns the dispatcher mounted at the longest prefix of the method
+// and a copy of the call naming the method within it, which remembers the
+// call it was copied from.
+func (dispatcher *MapDispatcher) mounted(call *Call) (Dispatcher, *Call) {
+	dispatcher.mtx.RLock()
+	defer dispatcher.mtx.RUnlock()
+
+	name := call.Method
+	for dot := strings.LastIndexByte(name, '.'); dot > 0; dot = strings.LastIndexByte(name[:dot], '.') {
+		mounted, ok := dispatcher.mounts[name[:dot]]
+		if ok {
+			inner := *call
+			inner.Method = name[dot+1:]
+			inner.origin = call.received()
+			return mounted, &inner
+		}
+	}
+	return nil, nil
+}
+
+// Group returns a group of the DefaultDispatcher
+func Group(name string) *MapDispatcher {
+	return DefaultDispatcher.Group(name)
+}
+
+// Mount mounts the dispatcher on the DefaultDispatcher
+func Mount(prefix string, mounted Dispatcher) error {
+	return DefaultDispatcher.Mount(prefix, mounted)
+}
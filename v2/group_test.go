@@ -0,0 +1,134 @@
+package jsonrpc
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMapDispatcher_Group(t *testing.T) {
+	dispatcher := NewMapDispatcher()
+	dispatcher.Register("ping", nameMethod)
+
+	venue := dispatcher.Group("venue")
+	venue.Register("get", nameMethod)
+	venue.Register("list", nameMethod)
+	venue.Group("seats").Register("get", nameMethod)
+	assert.Equal(t, venue, dispatcher.Group("venue"))
+
+	assert.Equal(t, "ping", dispatchCall(dispatcher, nil, "ping", "").Result)
+	assert.Equal(t, "get", dispatchCall(dispatcher, nil, "venue.get", "").Result)
+	assert.Equal(t, "list", dispatchCall(dispatcher, nil, "venue.list", "").Result)
+	assert.Equal(t, "get", dispatchCall(dispatcher, nil, "venue.seats.get", "").Result)
+
+	assert.Equal(t, CodeMethodNotFound, dispatchCall(dispatcher, nil, "venue.delete", "").Error.Code)
+	assert.Equal(t, CodeMethodNotFound, dispatchCall(dispatcher, nil, "booking.get", "").Error.Code)
+	assert.Equal(t, CodeMethodNotFound, dispatchCall(dispatcher, nil, "venue", "").Error.Code)
+	assert.Equal(t, CodeMethodNotFound, dispatchCall(dispatcher, nil, "get", "").Error.Code)
+}
+
+func TestMapDispatcher_Mount(t *testing.T) {
+	bookings := NewMapDispatcher()
+	bookings.Register("create", nameMethod)
+
+	dispatcher := NewMapDispatcher()
+	assert.Nil(t, dispatcher.Mount("booking", bookings))
+	assert.Nil(t, dispatcher.Mount("legacy.echo", Method(nameMethod)))
+	assert.NotNil(t, dispatcher.Mount("booking", bookings))
+	assert.NotNil(t, dispatcher.Mount("", bookings))
+	assert.NotNil(t, dispatcher.Mount("booking.", bookings))
+
+	// methods registered directly take precedence
+	dispatcher.Register("booking.cancel", nameMethod)
+
+	assert.Equal(t, "create", dispatchCall(dispatcher, nil, "booking.create", "").Result)
+	assert.Equal(t, "booking.cancel", dispatchCall(dispatcher, nil, "booking.cancel", "").Result)
+	assert.Equal(t, "anything", dispatchCall(dispatcher, nil, "legacy.echo.anything", "").Result)
+
+	// the mounted dispatcher is still usable on its own
+	assert.Equal(t, "create", dispatchCall(bookings, nil, "create", "").Result)
+}
+
+func TestMapDispatcher_Use(t *testing.T) {
+	var order []string
+	trace := func(name string) Middleware {
+		return func(next Dispatcher) Dispatcher {
+			return Method(func(resp *Response, call *Call, req *http.Request) {
+				order = append(order, name+":"+call.Method)
+				next.Dispatch(resp, call, req)
+			})
+		}
+	}
+
+	dispatcher := NewMapDispatcher()
+	dispatcher.Register("ping", nameMethod)
+	dispatcher.Use(trace("outer"), trace("inner"))
+
+	venue := dispatcher.Group("venue")
+	venue.Register("get", nameMethod)
+	venue.Use(trace("venue"))
+
+	booking := dispatcher.Group("booking")
+	booking.Register("create", nameMethod)
+
+	assert.Equal(t, "ping", dispatchCall(dispatcher, nil, "ping", "").Result)
+	assert.Equal(t, []string{"outer:ping", "inner:ping"}, order)
+
+	order = nil
+	assert.Equal(t, "get", dispatchCall(dispatcher, nil, "venue.get", "").Result)
+	assert.Equal(t, []string{"outer:venue.get", "inner:venue.get", "venue:get"}, order)
+
+	order = nil
+	assert.Equal(t, "create", dispatchCall(dispatcher, nil, "booking.create", "").Result)
+	assert.Equal(t, []string{"outer:booking.create", "inner:booking.create"}, order)
+}
+
+func TestMapDispatcher_Group_policies(t *testing.T) {
+	var calls int
+	dispatcher := NewMapDispatcher()
+	admin := dispatcher.Group("admin")
+	admin.RegisterWithPolicy("purge", func(resp *Response, call *Call, req *http.Request) {
+		calls++
+		resp.Result = true
+	}, &Policy{Roles: []string{"admin"}})
+	admin.RegisterSafe("stats", nameMethod)
+
+	// a cache in front of the group still checks the group's policies
+	admin.Use(func(next Dispatcher) Dispatcher {
+		caching := NewCachingDispatcher(next, 10)
+		caching.CacheMethod("purge", time.Minute)
+		return caching
+	})
+
+	assert.Nil(t, dispatchCall(dispatcher, requestAs(&Principal{Name: "root", Roles: []string{"admin"}}), "admin.purge", "").Error)
+	assert.Equal(t, CodeForbidden, dispatchCall(dispatcher, requestAs(&Principal{Name: "bob"}), "admin.purge", "").Error.Code)
+	assert.Equal(t, CodeUnauthenticated, dispatchCall(dispatcher, nil, "admin.purge", "").Error.Code)
+	assert.Equal(t, 1, calls)
+
+	// and the parent reports the group's safe methods
+	assert.True(t, dispatcher.IsSafe("admin.stats"))
+	assert.False(t, dispatcher.IsSafe("admin.purge"))
+	assert.False(t, dispatcher.IsSafe("stats"))
+}
+
+func TestGroup_http(t *testing.T) {
+	dispatcher := NewMapDispatcher()
+	dispatcher.Group("venue").Register("get", nameMethod)
+	server := httptest.NewServer(&Handler{Dispatcher: dispatcher})
+	defer server.Close()
+
+	response, err := http.Post(server.URL, "application/json", strings.NewReader(`{"jsonrpc": "2.0", "id": 7, "method": "venue.get"}`))
+	if !assert.Nil(t, err) {
+		t.FailNow()
+	}
+	defer response.Body.Close()
+
+	var resp Response
+	assert.Nil(t, DefaultJSONEngine.NewDecoder(response.Body).Decode(&resp))
+	assert.Equal(t, "get", resp.Result)
+	assert.Equal(t, float64(7), resp.ID)
+}
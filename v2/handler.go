@@ -3,24 +3,51 @@ package jsonrpc
 import (
 	"encoding/json"
 	"fmt"
-	"io/ioutil"
 	"net/http"
 	"sync"
 )
 
+// StreamedBatchContentType is the content type of streamed batch responses.
+// Clients asking for it in the Accept header receive each response in a batch
+// as a separate line of JSON as soon as it is ready, in the order the calls
+// complete.
+const StreamedBatchContentType = "application/x-ndjson"
+
 // DefaultHandler is the default Handler for serving requests quickly
 var DefaultHandler = &Handler{
 	Dispatcher: DefaultDispatcher,
 }
 
 // Handler provides the interface between http requests and the Dispatcher
+//
+// Request bodies sent with a gzip or deflate Content-Encoding are decoded, and
+// responses of at least CompressionThreshold bytes (DefaultCompressionThreshold
+// when zero) are compressed for clients that accept it unless
+// DisableCompression is set. Streamed responses are never compressed.
+// Request bodies larger than MaxBodySize bytes once decoded
+// (DefaultMaxBodySize when zero) are rejected with a 413 status.
+//
+// JSON controls how responses are encoded, falling back to IndentOutput and
+// EscapeHTML when nil. Requests whose Content-Type matches one of Codecs are
+// decoded with it and answered in the same format, streaming is only
+// available to JSON requests.
 type Handler struct {
-	Dispatcher Dispatcher
+	Dispatcher           Dispatcher
+	DisableCompression   bool
+	CompressionThreshold int
+	MaxBodySize          int64
+	JSON                 *JSONCodec
+	Codecs               []Codec
+}
+
+func (handler *Handler) serverError(w http.ResponseWriter, message string, code int) {
+	handler.serverErrorStatus(w, message, code, http.StatusOK)
 }
 
-func serverError(w http.ResponseWriter, message string, code int) {
+// serverErrorStatus writes an error response with the given http status.
+func (handler *Handler) serverErrorStatus(w http.ResponseWriter, message string, code int, status int) {
 	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
+	w.WriteHeader(status)
 
 	result := Response{
 		Version: "2.0",
@@ -31,7 +58,7 @@ func serverError(w http.ResponseWriter, message string, code int) {
 		},
 	}
 
-	resp, err := Marshal(&result)
+	resp, err := handler.JSON.Marshal(&result)
 
 	if err != nil {
 		w.Write([]byte(fmt.Sprintf(`{"jsonrpc": "2.0", "id": null, "error": {"code": %d, "message": "something went wrong!"}}`, CodeInternalError)))
@@ -41,84 +68,216 @@ func serverError(w http.ResponseWriter, message string, code int) {
 	w.Write(resp)
 }
 
-func (handler *Handler) dispatch(resp *Response, call *Call, req *http.Request, wg *sync.WaitGroup) {
+func dispatch(dispatcher Dispatcher, resp *Response, call *Call, req *http.Request, wg *sync.WaitGroup, ready func(*Response)) {
 	defer wg.Done()
-	handler.Dispatcher.Dispatch(resp, call, req)
+	dispatcher.Dispatch(resp, call, req)
+	if ready != nil {
+		ready(resp)
+	}
 }
 
-// ServeHTTP handles converting a http.Request into a Calls. Implements the
-// http.Handler interface
-func (handler *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-
-	if r.Method != http.MethodPost {
-		serverError(w, "jsonrpc: rpc calls should be done via a POST request", CodeInvalidRequest)
-		return
+// parseCalls decodes a message containing either a single call or a batch of
+// calls with the engine. single reports whether the message held a single
+// call.
+func parseCalls(body []byte, engine JSONEngine) (calls []*Call, single bool, err error) {
+	if isBatch(body) {
+		err = engine.Unmarshal(body, &calls)
+		if err != nil {
+			return nil, false, err
+		}
+		return calls, false, nil
 	}
 
-	body, err := ioutil.ReadAll(r.Body)
+	var call *Call
+	err = engine.Unmarshal(body, &call)
 	if err != nil {
-		serverError(w, err.Error(), CodeInvalidRequest)
-		return
+		return nil, false, err
 	}
+	return []*Call{call}, true, nil
+}
 
-	var calls []*Call
-	var single bool
-
-	err = json.Unmarshal(body, &calls)
-
-	if err != nil {
-		var call *Call
+// idKey returns the key used to detect duplicate IDs in a batch, reporting
+// false for IDs that are absent or cannot be compared.
+func idKey(id interface{}) (interface{}, bool) {
+	switch id.(type) {
+	case string, float64, json.Number, bool:
+		return id, true
+	default:
+		return nil, false
+	}
+}
 
-		serr := json.Unmarshal(body, &call)
+// dispatchCalls concurrently dispatches each of the calls and returns their
+// responses in the same order as the calls.
+//
+// When ready is not nil it is called with each response as soon as it is
+// complete, possibly from several goroutines at once.
+func dispatchCalls(dispatcher Dispatcher, calls []*Call, r *http.Request, ready func(*Response)) []*Response {
+	responses := make([]*Response, 0, len(calls))
 
-		if serr != nil {
-			serverError(w, err.Error(), CodeParseError)
-			return
+	if len(calls) == 1 && calls[0] != nil {
+		// a lone call needs neither duplicate detection nor a goroutine
+		resp := NewResponse(calls[0])
+		dispatcher.Dispatch(resp, calls[0], r)
+		if ready != nil {
+			ready(resp)
 		}
-		single = true
-		calls = append(calls, call)
+		return append(responses, resp)
 	}
 
-	var responses []*Response
 	var wg sync.WaitGroup
+	knownIDs := make(map[interface{}]bool, len(calls))
 
-	known_ids := make([]interface{}, 0)
-CALLS_LOOP:
 	for _, call := range calls {
+		if call == nil {
+			resp := &Response{
+				Version: "2.0",
+				Error: &Error{
+					Code:    CodeInvalidRequest,
+					Message: "jsonrpc: call must be an object",
+				},
+			}
+			responses = append(responses, resp)
+			if ready != nil {
+				ready(resp)
+			}
+			continue
+		}
 		resp := NewResponse(call)
 		responses = append(responses, resp)
-		if call.ID != nil {
-			for _, b := range known_ids {
-				if call.ID == b {
-					resp.Error = &Error{}
-					resp.Error.Code = CodeInvalidRequest
-					resp.Error.Message = "The 'id' element is not unique"
-					continue CALLS_LOOP
+		if key, ok := idKey(call.ID); ok {
+			if knownIDs[key] {
+				resp.Error = &Error{}
+				resp.Error.Code = CodeInvalidRequest
+				resp.Error.Message = "The 'id' element is not unique"
+				if ready != nil {
+					ready(resp)
 				}
+				continue
 			}
+			knownIDs[key] = true
 		}
 		wg.Add(1)
-		go handler.dispatch(resp, call, r, &wg)
-		known_ids = append(known_ids, call.ID)
+		go dispatch(dispatcher, resp, call, r, &wg, ready)
 	}
 
 	wg.Wait()
 
-	var data []byte
+	return responses
+}
+
+// serveStreamedBatch dispatches a batch of calls, writing each response to
+// the client as a line of JSON as soon as it is complete rather than waiting
+// for the whole batch.
+func (handler *Handler) serveStreamedBatch(w http.ResponseWriter, flusher http.Flusher, r *http.Request, calls []*Call) {
+	w.Header().Set("Content-Type", StreamedBatchContentType)
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	var mtx sync.Mutex
+	dispatchCalls(handler.Dispatcher, calls, r, func(resp *Response) {
+		data, err := handler.JSON.engine().Marshal(resp)
+		if err != nil {
+			data, _ = json.Marshal(&Response{
+				Version: resp.Version,
+				ID:      resp.ID,
+				Error: &Error{
+					Code:    CodeInternalError,
+					Message: err.Error(),
+				},
+			})
+		}
+
+		mtx.Lock()
+		defer mtx.Unlock()
+		w.Write(append(data, '\n'))
+		flusher.Flush()
+	})
+}
+
+// ServeHTTP handles converting a http.Request into a Calls. Implements the
+// http.Handler interface
+func (handler *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+
+	if r.Method == http.MethodGet && r.URL.Query().Get("method") != "" {
+		handler.serveGet(w, r)
+		return
+	}
+
+	if r.Method != http.MethodPost {
+		handler.serverError(w, "jsonrpc: rpc calls should be done via a POST request", CodeInvalidRequest)
+		return
+	}
+
+	in := getBuffer()
+	defer putBuffer(in)
+
+	err := readRequestBody(r, in, maxBodySize(handler.MaxBodySize))
+	if err == errBodyTooLarge {
+		handler.serverErrorStatus(w, err.Error(), CodeParseError, http.StatusRequestEntityTooLarge)
+		return
+	}
+	if err != nil {
+		handler.serverError(w, err.Error(), CodeInvalidRequest)
+		return
+	}
+	body := in.Bytes()
+
+	codec := codecFor(handler.Codecs, r.Header)
+	if codec != nil {
+		body, err = toJSON(codec, body)
+		if err != nil {
+			handler.serverError(w, err.Error(), CodeParseError)
+			return
+		}
+	}
+
+	calls, single, err := parseCalls(body, handler.JSON.engine())
+	if err != nil {
+		handler.serverError(w, err.Error(), CodeParseError)
+		return
+	}
+
+	flusher, canFlush := w.(http.Flusher)
+	canFlush = canFlush && codec == nil
+
+	if canFlush && accepts(r, EventStreamContentType) {
+		handler.serveEventStream(w, flusher, r, calls, single)
+		return
+	}
+
+	if canFlush && !single && accepts(r, StreamedBatchContentType) {
+		handler.serveStreamedBatch(w, flusher, r, calls)
+		return
+	}
+
+	responses := dispatchCalls(handler.Dispatcher, calls, r, nil)
+
+	// the responses are encoded into a pooled buffer rather than straight to
+	// the ResponseWriter so an encoding error can still be reported
+	out := getBuffer()
+	defer putBuffer(out)
+
 	if single {
-		data, err = Marshal(responses[0])
+		err = handler.JSON.encode(out, responses[0])
 	} else {
-		data, err = Marshal(responses)
+		err = handler.JSON.encode(out, responses)
+	}
+
+	data := out.Bytes()
+	contentType := "application/json"
+	if err == nil && codec != nil {
+		data, err = fromJSON(codec, data)
+		contentType = codec.ContentType()
 	}
 
 	if err != nil {
-		serverError(w, err.Error(), CodeInternalError)
+		handler.serverError(w, err.Error(), CodeInternalError)
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	w.Write(data)
+	w.Header().Set("Content-Type", contentType)
+	handler.writeBody(w, r, data)
 
 	return
 }
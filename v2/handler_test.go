@@ -3,6 +3,7 @@ package jsonrpc
 import (
 	"bytes"
 	"encoding/json"
+	"fmt"
 	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
@@ -33,7 +34,7 @@ func TestServeHTTP(t *testing.T) {
 			"abc123": 6.0,
 		},
 	}
-	handler := &Handler{dispatcher}
+	handler := &Handler{Dispatcher: dispatcher}
 	server := httptest.NewServer(handler)
 	defer server.Close()
 
@@ -65,7 +66,7 @@ func TestServeHTTP_batch(t *testing.T) {
 			"def456": 20.0,
 		},
 	}
-	handler := &Handler{dispatcher}
+	handler := &Handler{Dispatcher: dispatcher}
 	server := httptest.NewServer(handler)
 	defer server.Close()
 
@@ -107,7 +108,7 @@ func TestServeHTTP_batch_multiId(t *testing.T) {
 		resp.Result = 7.0
 	})
 
-	server := httptest.NewServer(&Handler{dispatcher})
+	server := httptest.NewServer(&Handler{Dispatcher: dispatcher})
 	defer server.Close()
 
 	buf := bytes.NewBufferString(`[
@@ -150,7 +151,7 @@ func TestServeHTTP_batch_someIds(t *testing.T) {
 		resp.Result = 7.0
 	})
 
-	server := httptest.NewServer(&Handler{dispatcher})
+	server := httptest.NewServer(&Handler{Dispatcher: dispatcher})
 	defer server.Close()
 
 	buf := bytes.NewBufferString(`[
@@ -201,7 +202,7 @@ func TestServeHTTP_batch_NoId(t *testing.T) {
 		resp.Result = 7.0
 	})
 
-	server := httptest.NewServer(&Handler{dispatcher})
+	server := httptest.NewServer(&Handler{Dispatcher: dispatcher})
 	defer server.Close()
 
 	buf := bytes.NewBufferString(`[
@@ -235,7 +236,7 @@ func TestServeHTTP_batch_NoId(t *testing.T) {
 
 func TestServeHTTP_with_get_request(t *testing.T) {
 	dispatcher := &fakeDispatcher{}
-	handler := &Handler{dispatcher}
+	handler := &Handler{Dispatcher: dispatcher}
 	server := httptest.NewServer(handler)
 	defer server.Close()
 
@@ -257,7 +258,7 @@ func TestServeHTTP_with_get_request(t *testing.T) {
 
 func TestServeHTTP_with_bad_data(t *testing.T) {
 	dispatcher := &fakeDispatcher{}
-	handler := &Handler{dispatcher}
+	handler := &Handler{Dispatcher: dispatcher}
 	server := httptest.NewServer(handler)
 	defer server.Close()
 
@@ -282,3 +283,147 @@ func TestServeHTTP_with_bad_data(t *testing.T) {
 	assert.Equal(t, CodeParseError, result.Error.Code)
 	assert.Equal(t, "invalid character 'h' looking for beginning of value", result.Error.Message)
 }
+
+func TestServeHTTP_streamed_batch(t *testing.T) {
+	release := make(chan struct{})
+	dispatcher := NewMapDispatcher()
+	dispatcher.Register("slow", func(resp *Response, call *Call, req *http.Request) {
+		<-release
+		resp.Result = "slow"
+	})
+	dispatcher.Register("fast", func(resp *Response, call *Call, req *http.Request) {
+		resp.Result = "fast"
+	})
+
+	server := httptest.NewServer(&Handler{Dispatcher: dispatcher})
+	defer server.Close()
+
+	buf := bytes.NewBufferString(`[
+		{"jsonrpc": "2.0", "id": "1", "method": "slow"},
+		{"jsonrpc": "2.0", "id": "2", "method": "fast"}
+	]`)
+	req, _ := http.NewRequest(http.MethodPost, server.URL, buf)
+	req.Header.Set("Accept", StreamedBatchContentType)
+
+	response, err := http.DefaultClient.Do(req)
+	if !assert.Nil(t, err) {
+		t.FailNow()
+	}
+	defer response.Body.Close()
+
+	assert.Equal(t, StreamedBatchContentType, response.Header.Get("Content-Type"))
+
+	decoder := json.NewDecoder(response.Body)
+
+	var first Response
+	err = decoder.Decode(&first)
+	assert.Nil(t, err)
+	assert.Equal(t, "2", first.ID)
+	assert.Equal(t, "fast", first.Result)
+
+	close(release)
+
+	var second Response
+	err = decoder.Decode(&second)
+	assert.Nil(t, err)
+	assert.Equal(t, "1", second.ID)
+	assert.Equal(t, "slow", second.Result)
+}
+
+// discardResponseWriter is a http.ResponseWriter that throws away the
+// response so benchmarks only measure the handler.
+type discardResponseWriter struct {
+	header http.Header
+}
+
+func (w *discardResponseWriter) Header() http.Header {
+	return w.header
+}
+
+func (w *discardResponseWriter) Write(data []byte) (int, error) {
+	return len(data), nil
+}
+
+func (w *discardResponseWriter) WriteHeader(status int) {}
+
+func benchmarkServeHTTP(b *testing.B, body []byte) {
+	dispatcher := NewMapDispatcher()
+	dispatcher.Register("add", func(resp *Response, call *Call, req *http.Request) {
+		var params []int
+		call.UnmarshalParams(&params)
+		result := 0
+		for _, n := range params {
+			result += n
+		}
+		resp.Result = result
+	})
+	handler := &Handler{Dispatcher: dispatcher, JSON: &JSONCodec{}}
+
+	reader := bytes.NewReader(body)
+	r := httptest.NewRequest(http.MethodPost, "/", nil)
+	r.Body = ioutil.NopCloser(reader)
+	w := &discardResponseWriter{header: http.Header{}}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		reader.Reset(body)
+		for key := range w.header {
+			delete(w.header, key)
+		}
+		handler.ServeHTTP(w, r)
+	}
+}
+
+func BenchmarkServeHTTP_single(b *testing.B) {
+	benchmarkServeHTTP(b, []byte(`{"jsonrpc": "2.0", "id": 1, "method": "add", "params": [1, 2, 3]}`))
+}
+
+func BenchmarkServeHTTP_batch(b *testing.B) {
+	var buf bytes.Buffer
+	buf.WriteString("[")
+	for i := 0; i < 50; i++ {
+		if i > 0 {
+			buf.WriteString(",")
+		}
+		fmt.Fprintf(&buf, `{"jsonrpc": "2.0", "id": %d, "method": "add", "params": [1, 2, 3]}`, i)
+	}
+	buf.WriteString("]")
+	benchmarkServeHTTP(b, buf.Bytes())
+}
+
+func TestDispatchCalls_ids(t *testing.T) {
+	calls, single, err := parseCalls([]byte(`[
+		{"jsonrpc": "2.0", "id": 1, "method": "echo"},
+		{"jsonrpc": "2.0", "id": "1", "method": "echo"},
+		{"jsonrpc": "2.0", "id": 1, "method": "echo"},
+		{"jsonrpc": "2.0", "id": {"a": 1}, "method": "echo"},
+		{"jsonrpc": "2.0", "id": {"a": 1}, "method": "echo"},
+		{"jsonrpc": "2.0", "method": "echo"},
+		{"jsonrpc": "2.0", "method": "echo"}
+	]`), StandardJSONEngine)
+	assert.Nil(t, err)
+	assert.False(t, single)
+
+	responses := dispatchCalls(newEchoDispatcher(), calls, nil, nil)
+	assert.Len(t, responses, 7)
+
+	for i, resp := range responses {
+		if i == 2 {
+			assert.Equal(t, CodeInvalidRequest, resp.Error.Code)
+		} else {
+			assert.Nil(t, resp.Error, i)
+		}
+	}
+}
+
+func TestServeHTTP_null(t *testing.T) {
+	handler := &Handler{Dispatcher: newEchoDispatcher()}
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/", bytes.NewBufferString(`null`)))
+
+	var resp Response
+	assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, CodeInvalidRequest, resp.Error.Code)
+}
@@ -0,0 +1,155 @@
+// Package jsoniter provides a JSONEngine backed by json-iterator, a faster
+// drop in replacement for encoding/json.
+//
+// Set it as the default for the whole process before serving or making calls:
+//
+//	jsonrpc.DefaultJSONEngine = jsoniter.NewEngine()
+//
+// or only for a single Handler, Client or Batch with JSONCodec.Engine.
+//
+// The engine trades allocations for speed. In this package's benchmarks a
+// Handler answers a batch in about two thirds of the time taken with
+// encoding/json using the same memory, and a Client decodes a large result in
+// about two thirds of the time but with over twice as many allocations, as
+// json-iterator grows slices an element at a time. Measure with your own types
+// before switching.
+//
+// Output matches encoding/json byte for byte unless it is indented, when
+// nested values are not always indented as deeply.
+package jsoniter
+
+import (
+	"io"
+	"sync"
+
+	"github.com/ingresso-group/gojsonrpc/v2"
+	jsoniter "github.com/json-iterator/go"
+)
+
+// StandardConfig is the configuration of jsoniter.ConfigCompatibleWithStandardLibrary,
+// behaving exactly like encoding/json.
+var StandardConfig = jsoniter.Config{
+	EscapeHTML:             true,
+	SortMapKeys:            true,
+	ValidateJsonRawMessage: true,
+}
+
+// Engine adapts a jsoniter.API to the jsonrpc.JSONEngine interface.
+//
+// Engines made with NewEngine or NewEngineWithConfig encode through pooled
+// streams of a frozen configuration for each indentation and HTML escaping
+// setting, rather than allocating a new buffer for every encoder.
+type Engine struct {
+	API jsoniter.API
+
+	config *jsoniter.Config
+	apis   map[encoderSettings]jsoniter.API
+	mtx    *sync.RWMutex
+}
+
+// encoderSettings are the settings of a JSONEncoder that need their own
+// frozen configuration.
+type encoderSettings struct {
+	indent     int
+	escapeHTML bool
+}
+
+// NewEngine returns a pointer to an Engine configured to behave exactly like
+// encoding/json.
+func NewEngine() *Engine {
+	return NewEngineWithConfig(StandardConfig)
+}
+
+// NewEngineWithConfig returns a pointer to an Engine with the configuration.
+func NewEngineWithConfig(config jsoniter.Config) *Engine {
+	return &Engine{
+		API:    config.Froze(),
+		config: &config,
+		apis:   make(map[encoderSettings]jsoniter.API),
+		mtx:    new(sync.RWMutex),
+	}
+}
+
+// Marshal returns the JSON encoding of v.
+func (engine *Engine) Marshal(v interface{}) ([]byte, error) {
+	return engine.API.Marshal(v)
+}
+
+// Unmarshal decodes the JSON data into v.
+func (engine *Engine) Unmarshal(data []byte, v interface{}) error {
+	return engine.API.Unmarshal(data, v)
+}
+
+// NewEncoder returns an encoder writing to w.
+func (engine *Engine) NewEncoder(w io.Writer) jsonrpc.JSONEncoder {
+	if engine.config == nil {
+		return engine.API.NewEncoder(w)
+	}
+	return &encoder{
+		engine:   engine,
+		writer:   w,
+		settings: encoderSettings{escapeHTML: true},
+	}
+}
+
+// NewDecoder returns a decoder reading from r.
+func (engine *Engine) NewDecoder(r io.Reader) jsonrpc.JSONDecoder {
+	return engine.API.NewDecoder(r)
+}
+
+// api returns the frozen configuration for the encoder settings, freezing it
+// on first use.
+func (engine *Engine) api(settings encoderSettings) jsoniter.API {
+	engine.mtx.RLock()
+	api, ok := engine.apis[settings]
+	engine.mtx.RUnlock()
+	if ok {
+		return api
+	}
+
+	engine.mtx.Lock()
+	defer engine.mtx.Unlock()
+
+	api, ok = engine.apis[settings]
+	if !ok {
+		config := *engine.config
+		config.IndentionStep = settings.indent
+		config.EscapeHTML = settings.escapeHTML
+		api = config.Froze()
+		engine.apis[settings] = api
+	}
+	return api
+}
+
+// encoder encodes values with a pooled stream, writing each one to the writer
+// followed by a newline as json.Encoder does.
+type encoder struct {
+	engine   *Engine
+	writer   io.Writer
+	settings encoderSettings
+}
+
+func (encoder *encoder) Encode(v interface{}) error {
+	api := encoder.engine.api(encoder.settings)
+	stream := api.BorrowStream(nil)
+	defer api.ReturnStream(stream)
+
+	stream.WriteVal(v)
+	stream.WriteRaw("\n")
+	if stream.Error != nil {
+		return stream.Error
+	}
+
+	_, err := encoder.writer.Write(stream.Buffer())
+	return err
+}
+
+// SetIndent sets the indentation, which json-iterator applies as a number of
+// spaces, ignoring the prefix.
+func (encoder *encoder) SetIndent(prefix string, indent string) {
+	encoder.settings.indent = len(indent)
+}
+
+func (encoder *encoder) SetEscapeHTML(on bool) {
+	encoder.settings.escapeHTML = on
+}
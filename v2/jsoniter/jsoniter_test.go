@@ -0,0 +1,221 @@
+package jsoniter
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ingresso-group/gojsonrpc/v2"
+	jsoniter "github.com/json-iterator/go"
+	"github.com/stretchr/testify/assert"
+)
+
+type performance struct {
+	ID     int      `json:"id"`
+	Venue  string   `json:"venue"`
+	Prices []int    `json:"prices"`
+	Tags   []string `json:"tags"`
+}
+
+type performanceQuery struct {
+	Venue string `json:"venue"`
+	Limit int    `json:"limit"`
+}
+
+func newDispatcher() *jsonrpc.MapDispatcher {
+	dispatcher := jsonrpc.NewMapDispatcher()
+	dispatcher.Register("performances", func(resp *jsonrpc.Response, call *jsonrpc.Call, req *http.Request) {
+		var query performanceQuery
+		err := call.UnmarshalParams(&query)
+		if err != nil {
+			resp.Error = &jsonrpc.Error{Code: jsonrpc.CodeInvalidParameters, Message: err.Error()}
+			return
+		}
+
+		performances := make([]performance, query.Limit)
+		for i := range performances {
+			performances[i] = performance{
+				ID:     i,
+				Venue:  query.Venue,
+				Prices: []int{2500, 4000, 6500},
+				Tags:   []string{"matinee", "<accessible>"},
+			}
+		}
+		resp.Result = performances
+	})
+	return dispatcher
+}
+
+func batchBody(calls int) []byte {
+	var buf bytes.Buffer
+	buf.WriteString("[")
+	for i := 0; i < calls; i++ {
+		if i > 0 {
+			buf.WriteString(",")
+		}
+		fmt.Fprintf(&buf, `{"jsonrpc": "2.0", "id": %d, "method": "performances", "params": {"venue": "palladium", "limit": 10}}`, i)
+	}
+	buf.WriteString("]")
+	return buf.Bytes()
+}
+
+func serve(handler http.Handler, body []byte) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(body)))
+	return w
+}
+
+func TestEngine_Handler(t *testing.T) {
+	body := batchBody(3)
+
+	standard := &jsonrpc.Handler{
+		Dispatcher: newDispatcher(),
+		JSON:       &jsonrpc.JSONCodec{},
+	}
+	fast := &jsonrpc.Handler{
+		Dispatcher: newDispatcher(),
+		JSON:       &jsonrpc.JSONCodec{Engine: NewEngine()},
+	}
+
+	// the output matches encoding/json byte for byte
+	assert.Equal(t, serve(standard, body).Body.String(), serve(fast, body).Body.String())
+}
+
+func TestEngine_NewEncoder(t *testing.T) {
+	value := map[string]performance{"<a>&": {ID: 1, Prices: []int{1, 2}, Tags: []string{"<b>"}}}
+
+	for _, engine := range []*Engine{NewEngine(), {API: jsoniter.ConfigCompatibleWithStandardLibrary}} {
+		for _, escape := range []bool{true, false} {
+			for _, indent := range []string{"", "  "} {
+				var expected, actual bytes.Buffer
+
+				standard := json.NewEncoder(&expected)
+				standard.SetIndent("", indent)
+				standard.SetEscapeHTML(escape)
+				assert.Nil(t, standard.Encode(value))
+
+				encoder := engine.NewEncoder(&actual)
+				encoder.SetIndent("", indent)
+				encoder.SetEscapeHTML(escape)
+				assert.Nil(t, encoder.Encode(value))
+				assert.Nil(t, encoder.Encode(value))
+
+				if indent == "" {
+					assert.Equal(t, expected.String()+expected.String(), actual.String())
+					continue
+				}
+
+				// json-iterator does not always indent nested values as deeply
+				decoder := json.NewDecoder(&actual)
+				for i := 0; i < 2; i++ {
+					var encoded json.RawMessage
+					assert.Nil(t, decoder.Decode(&encoded))
+					assert.JSONEq(t, expected.String(), string(encoded))
+				}
+			}
+		}
+	}
+
+	// values that cannot be encoded are reported
+	assert.NotNil(t, NewEngine().NewEncoder(ioutil.Discard).Encode(func() {}))
+}
+
+func TestEngine_Client(t *testing.T) {
+	server := httptest.NewServer(&jsonrpc.Handler{Dispatcher: newDispatcher()})
+	defer server.Close()
+
+	client := jsonrpc.NewClient()
+	client.JSON = &jsonrpc.JSONCodec{Engine: NewEngine(), UseNumber: true}
+
+	var result []map[string]interface{}
+	err := client.Call(server.URL, "performances", &performanceQuery{Venue: "palladium", Limit: 2}, &result)
+	assert.Nil(t, err)
+	assert.Len(t, result, 2)
+	assert.Equal(t, json.Number("1"), result[1]["id"])
+	assert.Equal(t, "palladium", result[1]["venue"])
+}
+
+func TestEngine_default(t *testing.T) {
+	defer func(engine jsonrpc.JSONEngine) {
+		jsonrpc.DefaultJSONEngine = engine
+	}(jsonrpc.DefaultJSONEngine)
+	jsonrpc.DefaultJSONEngine = NewEngine()
+
+	call := &jsonrpc.Call{Params: json.RawMessage(`{"venue": "palladium", "limit": 3}`)}
+	var query performanceQuery
+	assert.Nil(t, call.UnmarshalParams(&query))
+	assert.Equal(t, performanceQuery{Venue: "palladium", Limit: 3}, query)
+
+	data, err := jsonrpc.Marshal(&query)
+	assert.Nil(t, err)
+	assert.Contains(t, string(data), `"venue": "palladium"`)
+}
+
+func benchmarkHandler(b *testing.B, engine jsonrpc.JSONEngine) {
+	handler := &jsonrpc.Handler{
+		Dispatcher:         newDispatcher(),
+		DisableCompression: true,
+		JSON:               &jsonrpc.JSONCodec{Engine: engine},
+	}
+	body := batchBody(20)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(body)))
+	}
+}
+
+func BenchmarkHandler_standard(b *testing.B) {
+	benchmarkHandler(b, jsonrpc.StandardJSONEngine)
+}
+
+func BenchmarkHandler_jsoniter(b *testing.B) {
+	benchmarkHandler(b, NewEngine())
+}
+
+// cannedTransport answers every request with the same body.
+type cannedTransport struct {
+	body []byte
+}
+
+func (transport *cannedTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Header:     http.Header{"Content-Type": []string{"application/json"}},
+		Body:       ioutil.NopCloser(bytes.NewReader(transport.body)),
+		Request:    req,
+	}, nil
+}
+
+func benchmarkClient(b *testing.B, engine jsonrpc.JSONEngine) {
+	handler := &jsonrpc.Handler{Dispatcher: newDispatcher(), JSON: &jsonrpc.JSONCodec{}}
+	body := serve(handler, []byte(`{"jsonrpc": "2.0", "id": "1", "method": "performances", "params": {"venue": "palladium", "limit": 200}}`)).Body.Bytes()
+
+	client := jsonrpc.NewClient()
+	client.HTTPClient = &http.Client{Transport: &cannedTransport{body: body}}
+	client.JSON = &jsonrpc.JSONCodec{Engine: engine}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		var result []performance
+		err := client.Call("http://localhost", "performances", &performanceQuery{Venue: "palladium", Limit: 200}, &result)
+		if err != nil || len(result) != 200 || result[0].Venue != "palladium" {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkClient_standard(b *testing.B) {
+	benchmarkClient(b, jsonrpc.StandardJSONEngine)
+}
+
+func BenchmarkClient_jsoniter(b *testing.B) {
+	benchmarkClient(b, NewEngine())
+}
@@ -0,0 +1,34 @@
+// Package msgpack provides a MessagePack Codec for JSONRPC handlers and
+// clients.
+package msgpack
+
+import (
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+// ContentType is the content type of MessagePack bodies.
+const ContentType = "application/msgpack"
+
+// Codec encodes bodies as MessagePack. Implements the jsonrpc.Codec
+// interface.
+type Codec struct{}
+
+// NewCodec returns a pointer to a MessagePack Codec.
+func NewCodec() *Codec {
+	return &Codec{}
+}
+
+// ContentType returns the content type of MessagePack bodies.
+func (codec *Codec) ContentType() string {
+	return ContentType
+}
+
+// Marshal returns the MessagePack encoding of v.
+func (codec *Codec) Marshal(v interface{}) ([]byte, error) {
+	return msgpack.Marshal(v)
+}
+
+// Unmarshal decodes the MessagePack data into v.
+func (codec *Codec) Unmarshal(data []byte, v interface{}) error {
+	return msgpack.Unmarshal(data, v)
+}
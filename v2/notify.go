@@ -0,0 +1,57 @@
+package jsonrpc
+
+import (
+	"context"
+	"errors"
+	"net/http"
+)
+
+// ErrNoNotifier is returned by Notify when the client that made the call
+// cannot receive notifications.
+var ErrNoNotifier = errors.New("jsonrpc: client cannot receive notifications for this call")
+
+// A Notifier sends notifications to the client that made a call, such as
+// progress updates from a long running method.
+type Notifier interface {
+	Notify(method string, params interface{}) error
+}
+
+type notifierContextKey struct{}
+
+// withNotifier returns a copy of the request carrying the Notifier.
+func withNotifier(req *http.Request, notifier Notifier) *http.Request {
+	return req.WithContext(context.WithValue(req.Context(), notifierContextKey{}, notifier))
+}
+
+// NotifierFromRequest returns a Notifier for the client that made the call,
+// or nil if it cannot receive notifications.
+//
+// Calls made over a persistent connection can always be notified through the
+// connection's Peer. Calls made over http can be notified when the client
+// asked for an event stream.
+func NotifierFromRequest(req *http.Request) Notifier {
+	if req == nil {
+		return nil
+	}
+
+	notifier, ok := req.Context().Value(notifierContextKey{}).(Notifier)
+	if ok {
+		return notifier
+	}
+
+	peer := PeerFromRequest(req)
+	if peer != nil {
+		return peer
+	}
+	return nil
+}
+
+// Notify sends a notification to the client that made the call, returning
+// ErrNoNotifier if it cannot receive one.
+func Notify(req *http.Request, method string, params interface{}) error {
+	notifier := NotifierFromRequest(req)
+	if notifier == nil {
+		return ErrNoNotifier
+	}
+	return notifier.Notify(method, params)
+}
@@ -0,0 +1,36 @@
+package jsonrpc
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type recordingNotifier struct {
+	methods []string
+}
+
+func (notifier *recordingNotifier) Notify(method string, params interface{}) error {
+	notifier.methods = append(notifier.methods, method)
+	return nil
+}
+
+func TestNotify(t *testing.T) {
+	assert.Equal(t, ErrNoNotifier, Notify(nil, "progress", 1))
+
+	req, _ := http.NewRequest(http.MethodPost, "/", nil)
+	assert.Nil(t, NotifierFromRequest(req))
+	assert.Equal(t, ErrNoNotifier, Notify(req, "progress", 1))
+
+	notifier := &recordingNotifier{}
+	req = withNotifier(req, notifier)
+	assert.Nil(t, Notify(req, "progress", 1))
+	assert.Equal(t, []string{"progress"}, notifier.methods)
+}
+
+func TestNotifierFromRequest_peer(t *testing.T) {
+	_, conn := newPipeConns()
+	peer := newPeer(conn, nil, newConnRequest(nil))
+	assert.Equal(t, peer, NotifierFromRequest(peer.req))
+}
@@ -0,0 +1,337 @@
+package jsonrpc
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+	"sort"
+)
+
+// OpenRPCVersion is the version of the OpenRPC specification followed by the
+// documents generated by a MapDispatcher.
+const OpenRPCVersion = "1.2.6"
+
+// DiscoverMethod is the name of the method answering with the OpenRPC
+// document of a server, registered with RegisterDiscover.
+const DiscoverMethod = "rpc.discover"
+
+// MethodInfo describes a method in the OpenRPC document of a MapDispatcher.
+//
+// Params and Result are values of the types the method takes and returns,
+// such as GetVenueParams{} and (*Venue)(nil), from which JSON Schemas are
+// reflected. Params that are structs are described by name, one param per
+// field, and other params as a single param given by position. A nil Params
+// describes a method taking no params.
+type MethodInfo struct {
+	Summary     string
+	Description string
+	Params      interface{}
+	Result      interface{}
+	Errors      []*Error
+	Examples    []*Example
+	Deprecated  bool
+}
+
+// Example is an example of a call to a method and its result.
+type Example struct {
+	Name    string
+	Summary string
+	Params  interface{}
+	Result  interface{}
+}
+
+// OpenRPCDocument is an OpenRPC document describing the methods of a server.
+type OpenRPCDocument struct {
+	OpenRPC    string             `json:"openrpc"`
+	Info       OpenRPCInfo        `json:"info"`
+	Methods    []*OpenRPCMethod   `json:"methods"`
+	Components *OpenRPCComponents `json:"components,omitempty"`
+}
+
+// OpenRPCInfo holds the title and version of the API in an OpenRPC document.
+type OpenRPCInfo struct {
+	Title       string `json:"title"`
+	Description string `json:"description,omitempty"`
+	Version     string `json:"version"`
+}
+
+// OpenRPCMethod describes a method in an OpenRPC document.
+type OpenRPCMethod struct {
+	Name           string               `json:"name"`
+	Summary        string               `json:"summary,omitempty"`
+	Description    string               `json:"description,omitempty"`
+	ParamStructure string               `json:"paramStructure,omitempty"`
+	Params         []*ContentDescriptor `json:"params"`
+	Result         *ContentDescriptor   `json:"result,omitempty"`
+	Errors         []*OpenRPCError      `json:"errors,omitempty"`
+	Examples       []*OpenRPCExample    `json:"examples,omitempty"`
+	Deprecated     bool                 `json:"deprecated,omitempty"`
+}
+
+// ContentDescriptor describes a param or result of a method in an OpenRPC
+// document.
+type ContentDescriptor struct {
+	Name        string  `json:"name"`
+	Summary     string  `json:"summary,omitempty"`
+	Description string  `json:"description,omitempty"`
+	Required    bool    `json:"required,omitempty"`
+	Schema      *Schema `json:"schema"`
+}
+
+// OpenRPCError describes an error a method may respond with in an OpenRPC
+// document.
+type OpenRPCError struct {
+	Code    int         `json:"code"`
+	Message string      `json:"message"`
+	Data    interface{} `json:"data,omitempty"`
+}
+
+// OpenRPCExample is an example call in an OpenRPC document.
+type OpenRPCExample struct {
+	Name    string                 `json:"name"`
+	Summary string                 `json:"summary,omitempty"`
+	Params  []*OpenRPCExampleValue `json:"params"`
+	Result  *OpenRPCExampleValue   `json:"result,omitempty"`
+}
+
+// OpenRPCExampleValue is an example param or result in an OpenRPC document.
+type OpenRPCExampleValue struct {
+	Name  string      `json:"name"`
+	Value interface{} `json:"value"`
+}
+
+// OpenRPCComponents holds the schemas referred to elsewhere in an OpenRPC
+// document.
+type OpenRPCComponents struct {
+	Schemas map[string]*Schema `json:"schemas,omitempty"`
+}
+
+// RegisterWithInfo registers a method described by the info in the
+// dispatcher's OpenRPC document.
+func (dispatcher *MapDispatcher) RegisterWithInfo(name string, method Method, info *MethodInfo) error {
+	err := dispatcher.Register(name, method)
+	if err != nil {
+		return err
+	}
+
+	dispatcher.mtx.Lock()
+	defer dispatcher.mtx.Unlock()
+
+	dispatcher.infos[name] = info
+	return nil
+}
+
+// Describe sets the info describing a registered method. The types of the
+// params and result of methods registered with RegisterService are kept when
+// the info has none.
+func (dispatcher *MapDispatcher) Describe(name string, info *MethodInfo) error {
+	dispatcher.mtx.Lock()
+	defer dispatcher.mtx.Unlock()
+
+	_, ok := dispatcher.methods[name]
+	if !ok {
+		return fmt.Errorf("jsonrpc: unable to describe method with name %s as it does not exist", name)
+	}
+
+	described := *info
+	if existing, ok := dispatcher.infos[name]; ok {
+		if described.Params == nil {
+			described.Params = existing.Params
+		}
+		if described.Result == nil {
+			described.Result = existing.Result
+		}
+	}
+
+	dispatcher.infos[name] = &described
+	return nil
+}
+
+// OpenRPC returns an OpenRPC document describing the methods of the
+// dispatcher and of its groups, in order of name.
+func (dispatcher *MapDispatcher) OpenRPC(info OpenRPCInfo) *OpenRPCDocument {
+	generator := newSchemaGenerator()
+
+	methods := dispatcher.describe("", generator)
+	sort.Slice(methods, func(i, j int) bool {
+		return methods[i].Name < methods[j].Name
+	})
+
+	document := &OpenRPCDocument{
+		OpenRPC: OpenRPCVersion,
+		Info:    info,
+		Methods: methods,
+	}
+	if len(generator.schemas) > 0 {
+		document.Components = &OpenRPCComponents{Schemas: generator.schemas}
+	}
+	return document
+}
+
+// RegisterDiscover registers the DiscoverMethod, answering with the OpenRPC
+// document of the dispatcher as it is when called. The method is safe, so the
+// document can also be fetched with a GET request.
+func (dispatcher *MapDispatcher) RegisterDiscover(info OpenRPCInfo) error {
+	return dispatcher.RegisterSafe(DiscoverMethod, func(resp *Response, call *Call, req *http.Request) {
+		resp.Result = dispatcher.OpenRPC(info)
+	})
+}
+
+// describe returns the OpenRPC methods of the dispatcher, with the prefix in
+// front of their names.
+func (dispatcher *MapDispatcher) describe(prefix string, generator *schemaGenerator) []*OpenRPCMethod {
+	dispatcher.mtx.RLock()
+	infos := make(map[string]*MethodInfo, len(dispatcher.methods))
+	for name := range dispatcher.methods {
+		infos[name] = dispatcher.infos[name]
+	}
+	mounts := make(map[string]Dispatcher, len(dispatcher.mounts))
+	for name, mounted := range dispatcher.mounts {
+		mounts[name] = mounted
+	}
+	dispatcher.mtx.RUnlock()
+
+	methods := make([]*OpenRPCMethod, 0, len(infos))
+	for name, info := range infos {
+		if name == DiscoverMethod {
+			// the specification leaves rpc.discover out of the document
+			continue
+		}
+		methods = append(methods, describeMethod(prefix+name, info, generator))
+	}
+
+	for name, mounted := range mounts {
+		switch mounted := mounted.(type) {
+		case *MapDispatcher:
+			methods = append(methods, mounted.describe(prefix+name+".", generator)...)
+		case methodLister:
+			for _, method := range mounted.Methods() {
+				methods = append(methods, describeMethod(prefix+name+"."+method, nil, generator))
+			}
+		}
+	}
+	return methods
+}
+
+// describeMethod returns the OpenRPC method described by the info, which may
+// be nil.
+func describeMethod(name string, info *MethodInfo, generator *schemaGenerator) *OpenRPCMethod {
+	method := &OpenRPCMethod{
+		Name:   name,
+		Params: []*ContentDescriptor{},
+		Result: &ContentDescriptor{Name: "result", Schema: &Schema{}},
+	}
+	if info == nil {
+		return method
+	}
+
+	method.Summary = info.Summary
+	method.Description = info.Description
+	method.Deprecated = info.Deprecated
+
+	if info.Params != nil {
+		method.ParamStructure, method.Params = describeParams(reflect.TypeOf(info.Params), generator)
+	}
+
+	if info.Result != nil {
+		method.Result.Schema = generator.schema(reflect.TypeOf(info.Result))
+	}
+
+	for _, err := range info.Errors {
+		method.Errors = append(method.Errors, &OpenRPCError{
+			Code:    err.Code,
+			Message: err.Message,
+			Data:    err.Data,
+		})
+	}
+
+	for _, example := range info.Examples {
+		method.Examples = append(method.Examples, &OpenRPCExample{
+			Name:    example.Name,
+			Summary: example.Summary,
+			Params:  exampleParams(example.Params, method.ParamStructure),
+			Result:  &OpenRPCExampleValue{Name: "result", Value: example.Result},
+		})
+	}
+	return method
+}
+
+// describeParams returns the param structure and params of a method taking
+// params of the type, one param for each field of a struct. Other params are
+// left undescribed, as their fields or positions are not known until they
+// are sent.
+func describeParams(t reflect.Type, generator *schemaGenerator) (string, []*ContentDescriptor) {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	if t.Kind() != reflect.Struct {
+		return "", []*ContentDescriptor{}
+	}
+
+	schema := generator.structSchema(t)
+	required := make(map[string]bool, len(schema.Required))
+	for _, name := range schema.Required {
+		required[name] = true
+	}
+
+	params := make([]*ContentDescriptor, 0, len(schema.order))
+	for _, name := range schema.order {
+		params = append(params, &ContentDescriptor{
+			Name:     name,
+			Required: required[name],
+			Schema:   schema.Properties[name],
+		})
+	}
+	return "by-name", params
+}
+
+// exampleParams returns the example params as OpenRPC example values, one for
+// each field when they are given by name and none when they are undescribed.
+func exampleParams(params interface{}, structure string) []*OpenRPCExampleValue {
+	values := []*OpenRPCExampleValue{}
+	if params == nil {
+		return values
+	}
+
+	if structure != "by-name" {
+		return values
+	}
+
+	// the fields are found as they would be encoded
+	data, err := Marshal(params)
+	if err != nil {
+		return values
+	}
+	var fields map[string]interface{}
+	err = DefaultJSONEngine.Unmarshal(data, &fields)
+	if err != nil {
+		return values
+	}
+
+	names := make([]string, 0, len(fields))
+	for name := range fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		values = append(values, &OpenRPCExampleValue{Name: name, Value: fields[name]})
+	}
+	return values
+}
+
+// RegisterWithInfo adds the described method to the DefaultDispatcher
+func RegisterWithInfo(name string, method Method, info *MethodInfo) error {
+	return DefaultDispatcher.RegisterWithInfo(name, method, info)
+}
+
+// Describe sets the info of a method of the DefaultDispatcher
+func Describe(name string, info *MethodInfo) error {
+	return DefaultDispatcher.Describe(name, info)
+}
+
+// RegisterDiscover adds the DiscoverMethod to the DefaultDispatcher
+func RegisterDiscover(info OpenRPCInfo) error {
+	return DefaultDispatcher.RegisterDiscover(info)
+}
@@ -0,0 +1,157 @@
+package jsonrpc
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type searchParams struct {
+	Query string `json:"query"`
+	Limit int    `json:"limit,omitempty"`
+}
+
+func newDescribedDispatcher() *MapDispatcher {
+	dispatcher := NewMapDispatcher()
+	dispatcher.RegisterService(newVenueService(), SnakeCaseName)
+	dispatcher.RegisterWithInfo("search", nameMethod, &MethodInfo{
+		Summary:     "Search for venues",
+		Description: "Finds venues whose names match the query.",
+		Params:      searchParams{},
+		Result:      []Venue{},
+		Errors:      []*Error{{Code: 404, Message: "no venues found"}},
+		Examples: []*Example{{
+			Name:   "apollo",
+			Params: searchParams{Query: "apollo", Limit: 1},
+			Result: []Venue{{ID: 1, Name: "Apollo"}},
+		}},
+	})
+	dispatcher.Register("ping", nameMethod)
+	dispatcher.Group("admin").RegisterWithInfo("purge", nameMethod, &MethodInfo{Deprecated: true})
+	dispatcher.RegisterDiscover(OpenRPCInfo{Title: "Venues", Version: "1.0.0"})
+	return dispatcher
+}
+
+func findMethod(document *OpenRPCDocument, name string) *OpenRPCMethod {
+	for _, method := range document.Methods {
+		if method.Name == name {
+			return method
+		}
+	}
+	return nil
+}
+
+func TestMapDispatcher_OpenRPC(t *testing.T) {
+	document := newDescribedDispatcher().OpenRPC(OpenRPCInfo{Title: "Venues", Version: "1.0.0"})
+
+	assert.Equal(t, OpenRPCVersion, document.OpenRPC)
+	assert.Equal(t, "Venues", document.Info.Title)
+
+	names := []string{}
+	for _, method := range document.Methods {
+		names = append(names, method.Name)
+	}
+	assert.Equal(t, []string{
+		"admin.purge",
+		"ping",
+		"search",
+		"venue_service.count",
+		"venue_service.get",
+		"venue_service.list_http_venues",
+		"venue_service.rename",
+	}, names)
+
+	search := findMethod(document, "search")
+	assert.Equal(t, "Search for venues", search.Summary)
+	assert.Equal(t, "by-name", search.ParamStructure)
+	assert.Equal(t, []*ContentDescriptor{
+		{Name: "query", Required: true, Schema: &Schema{Type: "string"}},
+		{Name: "limit", Schema: &Schema{Type: "integer"}},
+	}, search.Params)
+	assert.Equal(t, &Schema{Type: "array", Items: &Schema{Ref: "#/components/schemas/Venue"}}, search.Result.Schema)
+	assert.Equal(t, []*OpenRPCError{{Code: 404, Message: "no venues found"}}, search.Errors)
+	assert.Equal(t, []*OpenRPCExampleValue{
+		{Name: "limit", Value: float64(1)},
+		{Name: "query", Value: "apollo"},
+	}, search.Examples[0].Params)
+
+	get := findMethod(document, "venue_service.get")
+	assert.Equal(t, "by-name", get.ParamStructure)
+	assert.Equal(t, "id", get.Params[0].Name)
+	assert.Equal(t, &Schema{Ref: "#/components/schemas/Venue"}, get.Result.Schema)
+
+	list := findMethod(document, "venue_service.list_http_venues")
+	// params of other types are sent as they are, so they are left undescribed
+	assert.Equal(t, "", list.ParamStructure)
+	assert.Equal(t, []*ContentDescriptor{}, list.Params)
+
+	count := findMethod(document, "venue_service.count")
+	assert.Equal(t, []*ContentDescriptor{}, count.Params)
+	assert.Equal(t, &Schema{Type: "integer"}, count.Result.Schema)
+
+	ping := findMethod(document, "ping")
+	assert.Equal(t, &ContentDescriptor{Name: "result", Schema: &Schema{}}, ping.Result)
+
+	assert.True(t, findMethod(document, "admin.purge").Deprecated)
+
+	assert.Contains(t, document.Components.Schemas, "Venue")
+	// by-name params are described by their fields rather than referred to
+	assert.NotContains(t, document.Components.Schemas, "VenueParams")
+}
+
+func TestMapDispatcher_Describe(t *testing.T) {
+	dispatcher := newDescribedDispatcher()
+
+	assert.NotNil(t, dispatcher.Describe("missing", &MethodInfo{}))
+	assert.Nil(t, dispatcher.Describe("ping", &MethodInfo{Summary: "Checks the server is up", Result: ""}))
+	assert.Nil(t, dispatcher.Describe("venue_service.get", &MethodInfo{Summary: "Gets a venue"}))
+
+	document := dispatcher.OpenRPC(OpenRPCInfo{})
+
+	ping := findMethod(document, "ping")
+	assert.Equal(t, "Checks the server is up", ping.Summary)
+	assert.Equal(t, &Schema{Type: "string"}, ping.Result.Schema)
+
+	// the reflected types are kept
+	get := findMethod(document, "venue_service.get")
+	assert.Equal(t, "Gets a venue", get.Summary)
+	assert.Equal(t, "id", get.Params[0].Name)
+
+	dispatcher.Unregister("search")
+	assert.Nil(t, findMethod(dispatcher.OpenRPC(OpenRPCInfo{}), "search"))
+}
+
+func TestRegisterDiscover(t *testing.T) {
+	dispatcher := newDescribedDispatcher()
+	server := httptest.NewServer(&Handler{Dispatcher: dispatcher})
+	defer server.Close()
+
+	var document OpenRPCDocument
+	err := NewClient().Call(server.URL, DiscoverMethod, nil, &document)
+	assert.Nil(t, err)
+	assert.Equal(t, "Venues", document.Info.Title)
+	assert.Len(t, document.Methods, 7)
+	assert.Nil(t, findMethod(&document, DiscoverMethod))
+
+	// the document reflects methods registered later
+	dispatcher.Register("late", nameMethod)
+	err = NewClient().Call(server.URL, DiscoverMethod, nil, &document)
+	assert.Nil(t, err)
+	assert.NotNil(t, findMethod(&document, "late"))
+
+	// and can be fetched with a GET request
+	response, err := http.Get(server.URL + "?method=rpc.discover&id=1")
+	if !assert.Nil(t, err) {
+		t.FailNow()
+	}
+	defer response.Body.Close()
+
+	var resp struct {
+		Result OpenRPCDocument `json:"result"`
+	}
+	assert.Nil(t, json.NewDecoder(response.Body).Decode(&resp))
+	assert.Equal(t, OpenRPCVersion, resp.Result.OpenRPC)
+}
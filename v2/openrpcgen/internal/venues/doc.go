@@ -0,0 +1,5 @@
+// Package venues is an example of a client and server generated by
+// jsonrpc-gen, used to test the generator.
+package venues
+
+//go:generate go run ../../../cmd/jsonrpc-gen -server -out venues_gen.go venues.json
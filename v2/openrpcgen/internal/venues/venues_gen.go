@@ -0,0 +1,224 @@
+// Code generated by jsonrpc-gen from venues.json. DO NOT EDIT.
+
+package venues
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	jsonrpc "github.com/ingresso-group/gojsonrpc/v2"
+)
+
+// Venue is the Venue schema.
+type Venue struct {
+	Capacity *int64     `json:"capacity,omitempty"`
+	ID       string     `json:"id"`
+	Name     string     `json:"name"`
+	Opened   *time.Time `json:"opened,omitempty"`
+	Tags     []string   `json:"tags,omitempty"`
+}
+
+// VenueGetParams are the params of venue.get.
+type VenueGetParams struct {
+	VenueID string `json:"venue_id"`
+}
+
+// VenueSearchParams are the params of venue.search.
+type VenueSearchParams struct {
+	City  string `json:"city"`
+	Limit *int64 `json:"limit,omitempty"`
+}
+
+// Client calls the methods of Venues.
+type Client struct {
+	URL    string
+	Client *jsonrpc.Client
+}
+
+// NewClient returns a pointer to a Client calling the server at the url with
+// the jsonrpc.DefaultClient.
+func NewClient(url string) *Client {
+	return &Client{URL: url, Client: jsonrpc.DefaultClient}
+}
+
+func (client *Client) call(ctx context.Context, method string, params interface{}, result interface{}) error {
+	return client.Client.CallContext(ctx, client.URL, method, params, result)
+}
+
+// VenueGet calls venue.get: Get returns the venue with the ID.
+func (client *Client) VenueGet(ctx context.Context, params VenueGetParams) (Venue, error) {
+	var result Venue
+	err := client.call(ctx, "venue.get", params, &result)
+	return result, err
+}
+
+// VenueSearch calls venue.search: Search returns the venues in a city.
+func (client *Client) VenueSearch(ctx context.Context, params VenueSearchParams) ([]Venue, error) {
+	var result []Venue
+	err := client.call(ctx, "venue.search", params, &result)
+	return result, err
+}
+
+// VenueCount calls venue.count: Count returns the number of venues.
+func (client *Client) VenueCount(ctx context.Context) (int64, error) {
+	var result int64
+	err := client.call(ctx, "venue.count", nil, &result)
+	return result, err
+}
+
+// VenueRename calls venue.rename: Rename renames a venue.
+//
+// Deprecated: venue.rename is deprecated.
+func (client *Client) VenueRename(ctx context.Context, id string, name string) (bool, error) {
+	var result bool
+	err := client.call(ctx, "venue.rename", []interface{}{id, name}, &result)
+	return result, err
+}
+
+// VenueClose calls venue.close: Close closes a venue.
+func (client *Client) VenueClose(ctx context.Context, id string) error {
+	return client.call(ctx, "venue.close", []interface{}{id}, nil)
+}
+
+// Server is implemented by servers of Venues, and registered on a
+// jsonrpc.MapDispatcher with RegisterServer.
+type Server interface {
+	// VenueGet calls venue.get: Get returns the venue with the ID.
+	VenueGet(ctx context.Context, params VenueGetParams) (Venue, error)
+	// VenueSearch calls venue.search: Search returns the venues in a city.
+	VenueSearch(ctx context.Context, params VenueSearchParams) ([]Venue, error)
+	// VenueCount calls venue.count: Count returns the number of venues.
+	VenueCount(ctx context.Context) (int64, error)
+	// VenueRename calls venue.rename: Rename renames a venue.
+	//
+	// Deprecated: venue.rename is deprecated.
+	VenueRename(ctx context.Context, id string, name string) (bool, error)
+	// VenueClose calls venue.close: Close closes a venue.
+	VenueClose(ctx context.Context, id string) error
+}
+
+// RegisterServer registers the methods of the server with the dispatcher,
+// the jsonrpc.DefaultDispatcher when nil.
+func RegisterServer(dispatcher *jsonrpc.MapDispatcher, server Server) error {
+	if dispatcher == nil {
+		dispatcher = jsonrpc.DefaultDispatcher
+	}
+
+	var err error
+
+	err = dispatcher.Register("venue.get", func(resp *jsonrpc.Response, call *jsonrpc.Call, req *http.Request) {
+		ctx := context.Background()
+		if req != nil {
+			ctx = req.Context()
+		}
+
+		var params VenueGetParams
+		err := call.UnmarshalParams(&params)
+		if err != nil {
+			resp.Error = &jsonrpc.Error{Code: jsonrpc.CodeInvalidParameters, Message: err.Error()}
+			return
+		}
+
+		result, err := server.VenueGet(ctx, params)
+		if err != nil {
+			resp.Error = jsonrpc.ToError(err)
+			return
+		}
+		resp.Result = result
+	})
+	if err != nil {
+		return err
+	}
+
+	err = dispatcher.Register("venue.search", func(resp *jsonrpc.Response, call *jsonrpc.Call, req *http.Request) {
+		ctx := context.Background()
+		if req != nil {
+			ctx = req.Context()
+		}
+
+		var params VenueSearchParams
+		err := call.UnmarshalParams(&params)
+		if err != nil {
+			resp.Error = &jsonrpc.Error{Code: jsonrpc.CodeInvalidParameters, Message: err.Error()}
+			return
+		}
+
+		result, err := server.VenueSearch(ctx, params)
+		if err != nil {
+			resp.Error = jsonrpc.ToError(err)
+			return
+		}
+		resp.Result = result
+	})
+	if err != nil {
+		return err
+	}
+
+	err = dispatcher.Register("venue.count", func(resp *jsonrpc.Response, call *jsonrpc.Call, req *http.Request) {
+		ctx := context.Background()
+		if req != nil {
+			ctx = req.Context()
+		}
+
+		result, err := server.VenueCount(ctx)
+		if err != nil {
+			resp.Error = jsonrpc.ToError(err)
+			return
+		}
+		resp.Result = result
+	})
+	if err != nil {
+		return err
+	}
+
+	err = dispatcher.Register("venue.rename", func(resp *jsonrpc.Response, call *jsonrpc.Call, req *http.Request) {
+		ctx := context.Background()
+		if req != nil {
+			ctx = req.Context()
+		}
+
+		var id string
+		var name string
+		err := call.UnmarshalPositionalParams(&id, &name)
+		if err != nil {
+			resp.Error = &jsonrpc.Error{Code: jsonrpc.CodeInvalidParameters, Message: err.Error()}
+			return
+		}
+
+		result, err := server.VenueRename(ctx, id, name)
+		if err != nil {
+			resp.Error = jsonrpc.ToError(err)
+			return
+		}
+		resp.Result = result
+	})
+	if err != nil {
+		return err
+	}
+
+	err = dispatcher.Register("venue.close", func(resp *jsonrpc.Response, call *jsonrpc.Call, req *http.Request) {
+		ctx := context.Background()
+		if req != nil {
+			ctx = req.Context()
+		}
+
+		var id string
+		err := call.UnmarshalPositionalParams(&id)
+		if err != nil {
+			resp.Error = &jsonrpc.Error{Code: jsonrpc.CodeInvalidParameters, Message: err.Error()}
+			return
+		}
+
+		err = server.VenueClose(ctx, id)
+		if err != nil {
+			resp.Error = jsonrpc.ToError(err)
+			return
+		}
+	})
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
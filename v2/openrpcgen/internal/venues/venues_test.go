@@ -0,0 +1,136 @@
+package venues
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	jsonrpc "github.com/ingresso-group/gojsonrpc/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+type venueServer struct {
+	venues map[string]Venue
+}
+
+func (server *venueServer) VenueGet(ctx context.Context, params VenueGetParams) (Venue, error) {
+	venue, ok := server.venues[params.VenueID]
+	if !ok {
+		return Venue{}, &jsonrpc.Error{Code: 404, Message: "venue not found"}
+	}
+	return venue, nil
+}
+
+func (server *venueServer) VenueSearch(ctx context.Context, params VenueSearchParams) ([]Venue, error) {
+	venues := []Venue{}
+	for _, id := range []string{"lyceum", "palladium"} {
+		if params.Limit != nil && int64(len(venues)) == *params.Limit {
+			break
+		}
+		venues = append(venues, server.venues[id])
+	}
+	return venues, nil
+}
+
+func (server *venueServer) VenueCount(ctx context.Context) (int64, error) {
+	return int64(len(server.venues)), nil
+}
+
+func (server *venueServer) VenueRename(ctx context.Context, id string, name string) (bool, error) {
+	venue, ok := server.venues[id]
+	if !ok {
+		return false, nil
+	}
+	venue.Name = name
+	server.venues[id] = venue
+	return true, nil
+}
+
+func (server *venueServer) VenueClose(ctx context.Context, id string) error {
+	if _, ok := server.venues[id]; !ok {
+		return &jsonrpc.Error{Code: 404, Message: "venue not found"}
+	}
+	delete(server.venues, id)
+	return nil
+}
+
+func newVenueClient(t *testing.T) (*Client, func()) {
+	opened := time.Date(1904, time.December, 26, 0, 0, 0, 0, time.UTC)
+	capacity := int64(2286)
+
+	dispatcher := jsonrpc.NewMapDispatcher()
+	err := RegisterServer(dispatcher, &venueServer{venues: map[string]Venue{
+		"lyceum":    {ID: "lyceum", Name: "Lyceum Theatre", Capacity: &capacity, Opened: &opened, Tags: []string{"musical"}},
+		"palladium": {ID: "palladium", Name: "London Palladium"},
+	}})
+	assert.Nil(t, err)
+
+	server := httptest.NewServer(&jsonrpc.Handler{Dispatcher: dispatcher})
+	return NewClient(server.URL), server.Close
+}
+
+func TestGeneratedClient(t *testing.T) {
+	client, done := newVenueClient(t)
+	defer done()
+	ctx := context.Background()
+
+	venue, err := client.VenueGet(ctx, VenueGetParams{VenueID: "lyceum"})
+	assert.Nil(t, err)
+	assert.Equal(t, "Lyceum Theatre", venue.Name)
+	assert.Equal(t, int64(2286), *venue.Capacity)
+	assert.Equal(t, 1904, venue.Opened.Year())
+	assert.Equal(t, []string{"musical"}, venue.Tags)
+
+	limit := int64(1)
+	venues, err := client.VenueSearch(ctx, VenueSearchParams{City: "London", Limit: &limit})
+	assert.Nil(t, err)
+	assert.Len(t, venues, 1)
+
+	count, err := client.VenueCount(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(2), count)
+
+	renamed, err := client.VenueRename(ctx, "palladium", "The London Palladium")
+	assert.Nil(t, err)
+	assert.True(t, renamed)
+
+	venue, err = client.VenueGet(ctx, VenueGetParams{VenueID: "palladium"})
+	assert.Nil(t, err)
+	assert.Equal(t, "The London Palladium", venue.Name)
+	assert.Nil(t, venue.Capacity)
+
+	err = client.VenueClose(ctx, "palladium")
+	assert.Nil(t, err)
+
+	count, err = client.VenueCount(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(1), count)
+
+	err = client.VenueClose(ctx, "palladium")
+	assert.Equal(t, 404, err.(*jsonrpc.Error).Code)
+}
+
+func TestGeneratedClientError(t *testing.T) {
+	client, done := newVenueClient(t)
+	defer done()
+
+	_, err := client.VenueGet(context.Background(), VenueGetParams{VenueID: "globe"})
+	rpcErr, ok := err.(*jsonrpc.Error)
+	assert.True(t, ok)
+	assert.Equal(t, 404, rpcErr.Code)
+	assert.Equal(t, "venue not found", rpcErr.Message)
+}
+
+func TestGeneratedServerInvalidParams(t *testing.T) {
+	dispatcher := jsonrpc.NewMapDispatcher()
+	err := RegisterServer(dispatcher, &venueServer{})
+	assert.Nil(t, err)
+
+	resp := &jsonrpc.Response{}
+	dispatcher.Dispatch(resp, &jsonrpc.Call{Method: "venue.rename", Params: []byte(`["lyceum", 7]`)}, nil)
+	assert.Equal(t, jsonrpc.CodeInvalidParameters, resp.Error.Code)
+
+	err = RegisterServer(dispatcher, &venueServer{})
+	assert.NotNil(t, err)
+}
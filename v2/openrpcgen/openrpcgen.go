@@ -0,0 +1,642 @@
+// Package openrpcgen generates Go code from OpenRPC documents: typed client
+// stubs calling each method with a jsonrpc.Client and, optionally, a server
+// interface with the glue registering it on a jsonrpc.MapDispatcher.
+//
+// It is used by the jsonrpc-gen command, which is suited to go generate.
+package openrpcgen
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"go/format"
+	"go/token"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	jsonrpc "github.com/ingresso-group/gojsonrpc/v2"
+)
+
+// Options controls the code generated for a document.
+//
+// Package is the name of the generated package. Prefix is put in front of the
+// names of the generated Client, Server, NewClient and RegisterServer, so
+// several documents can be generated into one package. When Server is set a
+// Server interface and RegisterServer function are generated as well as the
+// client. Source names the document in the generated header.
+type Options struct {
+	Package string
+	Prefix  string
+	Server  bool
+	Source  string
+}
+
+// schemaRefPrefix is the prefix of references to the schemas held in the
+// components of a document.
+const schemaRefPrefix = "#/components/schemas/"
+
+// initialisms are kept in upper case in generated names, as golint expects.
+var initialisms = map[string]bool{
+	"API": true, "ASCII": true, "CPU": true, "CSS": true, "DNS": true,
+	"EOF": true, "HTML": true, "HTTP": true, "HTTPS": true, "ID": true,
+	"IP": true, "JSON": true, "RPC": true, "SQL": true, "TCP": true,
+	"TLS": true, "UDP": true, "UI": true, "URI": true, "URL": true,
+	"UUID": true, "XML": true,
+}
+
+// reservedArgs are the names used by the generated methods that params must
+// not be given.
+var reservedArgs = map[string]bool{
+	"ctx": true, "client": true, "result": true, "err": true, "req": true,
+	"resp": true, "call": true, "server": true, "dispatcher": true,
+}
+
+// method holds what is needed to generate the code of a method.
+type method struct {
+	doc    *jsonrpc.OpenRPCMethod
+	name   string
+	byName bool
+	params string
+	args   []arg
+	result string
+}
+
+// arg is a param given by position.
+type arg struct {
+	name string
+	typ  string
+}
+
+type generator struct {
+	document *jsonrpc.OpenRPCDocument
+	options  Options
+	imports  map[string]bool
+	names    map[string]bool
+	types    bytes.Buffer
+}
+
+// Generate returns the formatted Go source of the code for the document.
+func Generate(document *jsonrpc.OpenRPCDocument, options Options) ([]byte, error) {
+	if options.Package == "" {
+		return nil, errors.New("openrpcgen: no package name given")
+	}
+	if !isIdentifier(options.Package) {
+		return nil, fmt.Errorf("openrpcgen: %s is not a valid package name", options.Package)
+	}
+
+	g := &generator{
+		document: document,
+		options:  options,
+		imports:  map[string]bool{"context": true},
+		names:    make(map[string]bool),
+	}
+
+	var components map[string]*jsonrpc.Schema
+	if document.Components != nil {
+		components = document.Components.Schemas
+	}
+	keys := make([]string, 0, len(components))
+	for key := range components {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	// component names are reserved first so no other type takes them
+	for _, key := range keys {
+		name := exportedName(key)
+		if name == g.clientName() || name == g.serverName() {
+			return nil, fmt.Errorf("openrpcgen: schema %s clashes with the generated %s, set a prefix", key, name)
+		}
+		g.names[name] = true
+	}
+	g.names[g.clientName()] = true
+	g.names[g.serverName()] = true
+	for _, key := range keys {
+		g.declareComponent(exportedName(key), components[key])
+	}
+
+	methods := make([]*method, 0, len(document.Methods))
+	names := make(map[string]string, len(document.Methods))
+	for _, doc := range document.Methods {
+		m := g.method(doc)
+		if other, ok := names[m.name]; ok {
+			return nil, fmt.Errorf("openrpcgen: methods %s and %s are both named %s", other, doc.Name, m.name)
+		}
+		names[m.name] = doc.Name
+		methods = append(methods, m)
+	}
+
+	var code bytes.Buffer
+	g.writeClient(&code, methods)
+	if options.Server {
+		g.imports["net/http"] = true
+		g.writeServer(&code, methods)
+	}
+
+	var source bytes.Buffer
+	g.writeHeader(&source)
+	source.Write(g.types.Bytes())
+	source.Write(code.Bytes())
+
+	formatted, err := format.Source(source.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("openrpcgen: generated invalid code: %s", err)
+	}
+	return formatted, nil
+}
+
+func (g *generator) writeHeader(w *bytes.Buffer) {
+	if g.options.Source != "" {
+		fmt.Fprintf(w, "// Code generated by jsonrpc-gen from %s. DO NOT EDIT.\n\n", g.options.Source)
+	} else {
+		fmt.Fprintf(w, "// Code generated by jsonrpc-gen. DO NOT EDIT.\n\n")
+	}
+	fmt.Fprintf(w, "package %s\n\n", g.options.Package)
+
+	imports := make([]string, 0, len(g.imports))
+	for path := range g.imports {
+		imports = append(imports, path)
+	}
+	sort.Strings(imports)
+
+	fmt.Fprintf(w, "import (\n")
+	for _, path := range imports {
+		fmt.Fprintf(w, "\t%q\n", path)
+	}
+	fmt.Fprintf(w, "\n\tjsonrpc \"github.com/ingresso-group/gojsonrpc/v2\"\n)\n\n")
+}
+
+// method works out the names and types of the method's params and result,
+// declaring any types they need.
+func (g *generator) method(doc *jsonrpc.OpenRPCMethod) *method {
+	m := &method{
+		doc:    doc,
+		name:   exportedName(doc.Name),
+		byName: doc.ParamStructure == "by-name",
+	}
+
+	if m.byName && len(doc.Params) > 0 {
+		properties := make(map[string]*jsonrpc.Schema, len(doc.Params))
+		order := make([]string, 0, len(doc.Params))
+		var required []string
+		for _, param := range doc.Params {
+			properties[param.Name] = param.Schema
+			order = append(order, param.Name)
+			if param.Required {
+				required = append(required, param.Name)
+			}
+		}
+		comment := fmt.Sprintf("%s are the params of %s.", m.name+"Params", doc.Name)
+		m.params = g.declareStruct(m.name+"Params", comment, properties, order, required)
+	} else {
+		taken := make(map[string]bool, len(doc.Params))
+		for i, param := range doc.Params {
+			name := argName(param.Name)
+			if taken[name] {
+				name = fmt.Sprintf("%s%d", name, i+1)
+			}
+			taken[name] = true
+			m.args = append(m.args, arg{
+				name: name,
+				typ:  g.goType(param.Schema, m.name+exportedName(param.Name)),
+			})
+		}
+	}
+
+	if doc.Result != nil {
+		m.result = g.goType(doc.Result.Schema, m.name+"Result")
+	}
+	return m
+}
+
+// declareComponent declares the type of a schema in the document's
+// components.
+func (g *generator) declareComponent(name string, schema *jsonrpc.Schema) {
+	comment := fmt.Sprintf("%s is the %s schema.", name, name)
+	if schema != nil && schema.Description != "" {
+		comment = schema.Description
+	}
+
+	if schema != nil && schema.Ref == "" && schema.Type == "object" && len(schema.Properties) > 0 {
+		g.writeStruct(name, comment, schema.Properties, nil, schema.Required)
+		return
+	}
+
+	typ := g.goType(schema, name+"Value")
+	writeComment(&g.types, "", comment)
+	fmt.Fprintf(&g.types, "type %s %s\n\n", name, typ)
+}
+
+// declareStruct declares a struct type with a field for each of the
+// properties, returning the name it was given.
+func (g *generator) declareStruct(name string, comment string, properties map[string]*jsonrpc.Schema, order []string, required []string) string {
+	name = g.uniqueName(name)
+	g.writeStruct(name, comment, properties, order, required)
+	return name
+}
+
+// writeStruct writes the declaration of a struct type. Fields are in the
+// given order, or in order of name when there is none. Optional fields are
+// omitted when empty.
+func (g *generator) writeStruct(name string, comment string, properties map[string]*jsonrpc.Schema, order []string, required []string) {
+	if order == nil {
+		for property := range properties {
+			order = append(order, property)
+		}
+		sort.Strings(order)
+	}
+
+	isRequired := make(map[string]bool, len(required))
+	for _, property := range required {
+		isRequired[property] = true
+	}
+
+	// the fields are written to a buffer of their own as declaring their types
+	// may write other declarations
+	var fields bytes.Buffer
+	fieldNames := make(map[string]bool, len(order))
+	for _, property := range order {
+		schema := properties[property]
+
+		field := exportedName(property)
+		for fieldNames[field] {
+			field += "_"
+		}
+		fieldNames[field] = true
+
+		typ := g.goType(schema, name+field)
+		tag := property
+		if !isRequired[property] {
+			tag += ",omitempty"
+			if pointable(typ) {
+				typ = "*" + typ
+			}
+		}
+
+		if schema != nil && schema.Description != "" {
+			writeComment(&fields, "\t", schema.Description)
+		}
+		fmt.Fprintf(&fields, "\t%s %s `json:%s`\n", field, typ, strconv.Quote(tag))
+	}
+
+	writeComment(&g.types, "", comment)
+	fmt.Fprintf(&g.types, "type %s struct {\n%s}\n\n", name, fields.Bytes())
+}
+
+// goType returns the Go type of values of the schema, declaring a struct
+// named by the hint for objects with properties.
+func (g *generator) goType(schema *jsonrpc.Schema, hint string) string {
+	if schema == nil {
+		return "interface{}"
+	}
+
+	if schema.Ref != "" {
+		if !strings.HasPrefix(schema.Ref, schemaRefPrefix) {
+			return "interface{}"
+		}
+		return exportedName(strings.TrimPrefix(schema.Ref, schemaRefPrefix))
+	}
+
+	switch schema.Type {
+	case "string":
+		if schema.Format == "date-time" {
+			g.imports["time"] = true
+			return "time.Time"
+		}
+		if schema.ContentEncoding == "base64" {
+			return "[]byte"
+		}
+		return "string"
+	case "integer":
+		return "int64"
+	case "number":
+		return "float64"
+	case "boolean":
+		return "bool"
+	case "array":
+		return "[]" + g.goType(schema.Items, hint+"Item")
+	case "object":
+		if len(schema.Properties) > 0 {
+			comment := fmt.Sprintf("%s is generated from an inline schema.", hint)
+			if schema.Description != "" {
+				comment = schema.Description
+			}
+			return g.declareStruct(hint, comment, schema.Properties, nil, schema.Required)
+		}
+		if schema.AdditionalProperties != nil {
+			return "map[string]" + g.goType(schema.AdditionalProperties, hint+"Value")
+		}
+		return "map[string]interface{}"
+	default:
+		return "interface{}"
+	}
+}
+
+// uniqueName returns the name, with a number after it if it is taken.
+func (g *generator) uniqueName(name string) string {
+	unique := name
+	for i := 2; g.names[unique]; i++ {
+		unique = name + strconv.Itoa(i)
+	}
+	g.names[unique] = true
+	return unique
+}
+
+func (g *generator) clientName() string {
+	return g.options.Prefix + "Client"
+}
+
+func (g *generator) serverName() string {
+	return g.options.Prefix + "Server"
+}
+
+func (g *generator) title() string {
+	if g.document.Info.Title == "" {
+		return "the API"
+	}
+	return g.document.Info.Title
+}
+
+func (g *generator) writeClient(w *bytes.Buffer, methods []*method) {
+	client := g.clientName()
+
+	fmt.Fprintf(w, "// %s calls the methods of %s.\n", client, g.title())
+	fmt.Fprintf(w, "type %s struct {\n\tURL string\n\tClient *jsonrpc.Client\n}\n\n", client)
+
+	fmt.Fprintf(w, "// New%s returns a pointer to a %s calling the server at the url with\n", client, client)
+	fmt.Fprintf(w, "// the jsonrpc.DefaultClient.\n")
+	fmt.Fprintf(w, "func New%s(url string) *%s {\n", client, client)
+	fmt.Fprintf(w, "\treturn &%s{URL: url, Client: jsonrpc.DefaultClient}\n}\n\n", client)
+
+	fmt.Fprintf(w, "func (client *%s) call(ctx context.Context, method string, params interface{}, result interface{}) error {\n", client)
+	fmt.Fprintf(w, "\treturn client.Client.CallContext(ctx, client.URL, method, params, result)\n}\n\n")
+
+	for _, m := range methods {
+		writeMethodComment(w, "", m)
+
+		params := "nil"
+		signature := "ctx context.Context"
+		if m.params != "" {
+			params = "params"
+			signature += ", params " + m.params
+		} else if len(m.args) > 0 {
+			names := make([]string, 0, len(m.args))
+			for _, a := range m.args {
+				names = append(names, a.name)
+				signature += ", " + a.name + " " + a.typ
+			}
+			params = "[]interface{}{" + strings.Join(names, ", ") + "}"
+		}
+
+		if m.result == "" {
+			fmt.Fprintf(w, "func (client *%s) %s(%s) error {\n", client, m.name, signature)
+			fmt.Fprintf(w, "\treturn client.call(ctx, %q, %s, nil)\n}\n\n", m.doc.Name, params)
+			continue
+		}
+
+		fmt.Fprintf(w, "func (client *%s) %s(%s) (%s, error) {\n", client, m.name, signature, m.result)
+		fmt.Fprintf(w, "\tvar result %s\n", m.result)
+		fmt.Fprintf(w, "\terr := client.call(ctx, %q, %s, &result)\n", m.doc.Name, params)
+		fmt.Fprintf(w, "\treturn result, err\n}\n\n")
+	}
+}
+
+func (g *generator) writeServer(w *bytes.Buffer, methods []*method) {
+	server := g.serverName()
+
+	fmt.Fprintf(w, "// %s is implemented by servers of %s, and registered on a\n", server, g.title())
+	fmt.Fprintf(w, "// jsonrpc.MapDispatcher with Register%s.\n", server)
+	fmt.Fprintf(w, "type %s interface {\n", server)
+	for _, m := range methods {
+		writeMethodComment(w, "\t", m)
+		fmt.Fprintf(w, "\t%s\n", serverSignature(m))
+	}
+	fmt.Fprintf(w, "}\n\n")
+
+	fmt.Fprintf(w, "// Register%s registers the methods of the server with the dispatcher,\n", server)
+	fmt.Fprintf(w, "// the jsonrpc.DefaultDispatcher when nil.\n")
+	fmt.Fprintf(w, "func Register%s(dispatcher *jsonrpc.MapDispatcher, server %s) error {\n", server, server)
+	fmt.Fprintf(w, "\tif dispatcher == nil {\n\t\tdispatcher = jsonrpc.DefaultDispatcher\n\t}\n\n")
+	if len(methods) > 0 {
+		fmt.Fprintf(w, "\tvar err error\n\n")
+	}
+
+	for _, m := range methods {
+		fmt.Fprintf(w, "\terr = dispatcher.Register(%q, func(resp *jsonrpc.Response, call *jsonrpc.Call, req *http.Request) {\n", m.doc.Name)
+
+		// the request is nil when the method is dispatched directly
+		fmt.Fprintf(w, "\t\tctx := context.Background()\n")
+		fmt.Fprintf(w, "\t\tif req != nil {\n\t\t\tctx = req.Context()\n\t\t}\n\n")
+
+		args := "ctx"
+		if m.params != "" {
+			fmt.Fprintf(w, "\t\tvar params %s\n", m.params)
+			fmt.Fprintf(w, "\t\terr := call.UnmarshalParams(&params)\n")
+			writeInvalidParams(w)
+			args += ", params"
+		} else if len(m.args) > 0 {
+			pointers := make([]string, 0, len(m.args))
+			for _, a := range m.args {
+				fmt.Fprintf(w, "\t\tvar %s %s\n", a.name, a.typ)
+				pointers = append(pointers, "&"+a.name)
+				args += ", " + a.name
+			}
+			fmt.Fprintf(w, "\t\terr := call.UnmarshalPositionalParams(%s)\n", strings.Join(pointers, ", "))
+			writeInvalidParams(w)
+		}
+
+		// err is already declared when there were params to decode
+		assign := ":="
+		if m.params != "" || len(m.args) > 0 {
+			assign = "="
+		}
+		if m.result == "" {
+			fmt.Fprintf(w, "\t\terr %s server.%s(%s)\n", assign, m.name, args)
+		} else {
+			fmt.Fprintf(w, "\t\tresult, err := server.%s(%s)\n", m.name, args)
+		}
+		fmt.Fprintf(w, "\t\tif err != nil {\n\t\t\tresp.Error = jsonrpc.ToError(err)\n\t\t\treturn\n\t\t}\n")
+		if m.result != "" {
+			fmt.Fprintf(w, "\t\tresp.Result = result\n")
+		}
+		fmt.Fprintf(w, "\t})\n")
+		fmt.Fprintf(w, "\tif err != nil {\n\t\treturn err\n\t}\n\n")
+	}
+
+	fmt.Fprintf(w, "\treturn nil\n}\n")
+}
+
+func writeInvalidParams(w *bytes.Buffer) {
+	fmt.Fprintf(w, "\t\tif err != nil {\n")
+	fmt.Fprintf(w, "\t\t\tresp.Error = &jsonrpc.Error{Code: jsonrpc.CodeInvalidParameters, Message: err.Error()}\n")
+	fmt.Fprintf(w, "\t\t\treturn\n\t\t}\n\n")
+}
+
+// serverSignature returns the signature of the method in the Server
+// interface.
+func serverSignature(m *method) string {
+	signature := m.name + "(ctx context.Context"
+	if m.params != "" {
+		signature += ", params " + m.params
+	}
+	for _, a := range m.args {
+		signature += ", " + a.name + " " + a.typ
+	}
+	if m.result == "" {
+		return signature + ") error"
+	}
+	return signature + ") (" + m.result + ", error)"
+}
+
+// writeMethodComment writes the doc comment of a method from its summary and
+// description.
+func writeMethodComment(w *bytes.Buffer, indent string, m *method) {
+	comment := fmt.Sprintf("%s calls %s.", m.name, m.doc.Name)
+	if m.doc.Summary != "" {
+		comment = fmt.Sprintf("%s calls %s: %s", m.name, m.doc.Name, m.doc.Summary)
+	}
+	if m.doc.Description != "" {
+		comment += "\n\n" + m.doc.Description
+	}
+	if m.doc.Deprecated {
+		comment += "\n\nDeprecated: " + m.doc.Name + " is deprecated."
+	}
+	writeComment(w, indent, comment)
+}
+
+// writeComment writes the text as a comment, wrapped at 80 columns.
+func writeComment(w *bytes.Buffer, indent string, text string) {
+	for i, paragraph := range strings.Split(text, "\n\n") {
+		if i > 0 {
+			fmt.Fprintf(w, "%s//\n", indent)
+		}
+
+		line := ""
+		for _, word := range strings.Fields(paragraph) {
+			if line != "" && len(indent)+3+len(line)+1+len(word) > 80 {
+				fmt.Fprintf(w, "%s// %s\n", indent, line)
+				line = ""
+			}
+			if line != "" {
+				line += " "
+			}
+			line += word
+		}
+		if line != "" {
+			fmt.Fprintf(w, "%s// %s\n", indent, line)
+		}
+	}
+}
+
+// pointable reports whether optional values of the type should be pointers,
+// so they can be told apart from zero values.
+func pointable(typ string) bool {
+	return !strings.HasPrefix(typ, "[]") && !strings.HasPrefix(typ, "map[") && typ != "interface{}"
+}
+
+// exportedName converts a name such as "venue_service.get_http_seats" into
+// an exported Go identifier such as "VenueServiceGetHTTPSeats".
+func exportedName(name string) string {
+	var builder strings.Builder
+	for _, word := range splitWords(name) {
+		if initialism, ok := asInitialism(word); ok {
+			builder.WriteString(initialism)
+			continue
+		}
+		runes := []rune(word)
+		runes[0] = unicode.ToUpper(runes[0])
+		builder.WriteString(string(runes))
+	}
+
+	exported := builder.String()
+	if exported == "" || !unicode.IsLetter([]rune(exported)[0]) {
+		exported = "X" + exported
+	}
+	return exported
+}
+
+// asInitialism returns the word written as an initialism, such as "ID" for
+// "id" and "IDs" for "ids", or false if it is not one.
+func asInitialism(word string) (string, bool) {
+	upper := strings.ToUpper(word)
+	if initialisms[upper] {
+		return upper, true
+	}
+	if strings.HasSuffix(word, "s") && initialisms[upper[:len(upper)-1]] {
+		return upper[:len(upper)-1] + "s", true
+	}
+	return "", false
+}
+
+// isIdentifier reports whether the name is a Go identifier that is not a
+// keyword.
+func isIdentifier(name string) bool {
+	if name == "" || token.Lookup(name).IsKeyword() {
+		return false
+	}
+	for i, r := range name {
+		if !unicode.IsLetter(r) && r != '_' && (i == 0 || !unicode.IsDigit(r)) {
+			return false
+		}
+	}
+	return true
+}
+
+// argName converts a param name into an unexported Go identifier that does
+// not clash with keywords or the names used by the generated code.
+func argName(name string) string {
+	exported := exportedName(name)
+	words := splitWords(name)
+
+	var arg string
+	initial := len(words) > 0
+	if initial {
+		_, initial = asInitialism(words[0])
+	}
+	if initial {
+		arg = strings.ToLower(words[0]) + exported[len(words[0]):]
+	} else {
+		runes := []rune(exported)
+		runes[0] = unicode.ToLower(runes[0])
+		arg = string(runes)
+	}
+
+	if token.Lookup(arg).IsKeyword() || reservedArgs[arg] {
+		arg += "Param"
+	}
+	return arg
+}
+
+// splitWords splits a name into words at punctuation and at changes from
+// lower to upper case.
+func splitWords(name string) []string {
+	var words []string
+	var word []rune
+
+	runes := []rune(name)
+	for i, r := range runes {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			if len(word) > 0 {
+				words = append(words, string(word))
+				word = nil
+			}
+			continue
+		}
+
+		if unicode.IsUpper(r) && len(word) > 0 {
+			previous := runes[i-1]
+			nextIsLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			if unicode.IsLower(previous) || unicode.IsDigit(previous) || (unicode.IsUpper(previous) && nextIsLower) {
+				words = append(words, string(word))
+				word = nil
+			}
+		}
+		word = append(word, r)
+	}
+	if len(word) > 0 {
+		words = append(words, string(word))
+	}
+	return words
+}
@@ -0,0 +1,148 @@
+package openrpcgen
+
+import (
+	"encoding/json"
+	"go/parser"
+	"go/token"
+	"io/ioutil"
+	"net/http"
+	"testing"
+
+	jsonrpc "github.com/ingresso-group/gojsonrpc/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExportedName(t *testing.T) {
+	assert.Equal(t, "VenueGet", exportedName("venue.get"))
+	assert.Equal(t, "GetVenueByID", exportedName("get_venue_by_id"))
+	assert.Equal(t, "GetURL", exportedName("getUrl"))
+	assert.Equal(t, "X2fa", exportedName("2fa"))
+	assert.Equal(t, "X", exportedName(""))
+	assert.Equal(t, "VenueIDs", exportedName("venue_ids"))
+}
+
+func TestArgName(t *testing.T) {
+	assert.Equal(t, "venueID", argName("venue_id"))
+	assert.Equal(t, "id", argName("id"))
+	assert.Equal(t, "ids", argName("ids"))
+	assert.Equal(t, "x", argName(""))
+	assert.Equal(t, "typeParam", argName("type"))
+	assert.Equal(t, "ctxParam", argName("ctx"))
+	assert.Equal(t, "resultParam", argName("result"))
+}
+
+func readDocument(t *testing.T, path string) *jsonrpc.OpenRPCDocument {
+	data, err := ioutil.ReadFile(path)
+	assert.Nil(t, err)
+
+	var document jsonrpc.OpenRPCDocument
+	err = json.Unmarshal(data, &document)
+	assert.Nil(t, err)
+	return &document
+}
+
+func TestGenerateMatchesExample(t *testing.T) {
+	document := readDocument(t, "internal/venues/venues.json")
+
+	code, err := Generate(document, Options{Package: "venues", Server: true, Source: "venues.json"})
+	assert.Nil(t, err)
+
+	expected, err := ioutil.ReadFile("internal/venues/venues_gen.go")
+	assert.Nil(t, err)
+	assert.Equal(t, string(expected), string(code), "run go generate ./... to update the example")
+}
+
+func TestGenerateWithoutServer(t *testing.T) {
+	document := readDocument(t, "internal/venues/venues.json")
+
+	code, err := Generate(document, Options{Package: "venues", Prefix: "Venue"})
+	assert.Nil(t, err)
+
+	source := string(code)
+	assert.Contains(t, source, "type VenueClient struct")
+	assert.Contains(t, source, "func NewVenueClient(url string) *VenueClient")
+	assert.NotContains(t, source, "VenueServer")
+	assert.NotContains(t, source, `"net/http"`)
+}
+
+type GetVenueParams struct {
+	VenueID string `json:"venue_id"`
+	Seats   *int   `json:"seats"`
+}
+
+type Venue struct {
+	ID     string             `json:"id"`
+	Photo  []byte             `json:"photo"`
+	Prices map[string]float64 `json:"prices"`
+	Parent *Venue             `json:"parent"`
+}
+
+func TestGenerateFromDispatcher(t *testing.T) {
+	method := func(resp *jsonrpc.Response, call *jsonrpc.Call, req *http.Request) {}
+
+	dispatcher := jsonrpc.NewMapDispatcher()
+	dispatcher.RegisterWithInfo("get_venue", method, &jsonrpc.MethodInfo{
+		Summary: "Returns a venue.",
+		Params:  GetVenueParams{},
+		Result:  (*Venue)(nil),
+	})
+	dispatcher.RegisterWithInfo("venue_ids", method, &jsonrpc.MethodInfo{
+		Params: []string{},
+		Result: []string{},
+	})
+
+	// the document is generated code's input after a trip through JSON
+	data, err := json.Marshal(dispatcher.OpenRPC(jsonrpc.OpenRPCInfo{Title: "Venues", Version: "1"}))
+	assert.Nil(t, err)
+	var document jsonrpc.OpenRPCDocument
+	err = json.Unmarshal(data, &document)
+	assert.Nil(t, err)
+
+	code, err := Generate(&document, Options{Package: "venues", Server: true})
+	assert.Nil(t, err)
+
+	_, err = parser.ParseFile(token.NewFileSet(), "venues_gen.go", code, 0)
+	assert.Nil(t, err)
+
+	source := string(code)
+	assert.Contains(t, source, "type GetVenueParams struct")
+	assert.Contains(t, source, "Seats   *int64 `json:\"seats,omitempty\"`")
+	assert.Contains(t, source, "Photo  []byte")
+	assert.Contains(t, source, "Prices map[string]float64")
+	assert.Contains(t, source, "Parent *Venue")
+	assert.Contains(t, source, "func (client *Client) GetVenue(ctx context.Context, params GetVenueParams) (Venue, error)")
+	// calls are made with the client's codecs and cache
+	assert.Contains(t, source, "client.Client.CallContext(ctx, client.URL, method, params, result)")
+	// params other than structs are left undescribed, so none are generated
+	assert.Contains(t, source, "func (client *Client) VenueIDs(ctx context.Context) ([]string, error)")
+	assert.NotContains(t, source, "call.UnmarshalPositionalParams(&params)")
+}
+
+func TestGenerateErrors(t *testing.T) {
+	document := &jsonrpc.OpenRPCDocument{
+		Methods: []*jsonrpc.OpenRPCMethod{
+			{Name: "venue.get"},
+			{Name: "venue_get"},
+		},
+	}
+
+	_, err := Generate(document, Options{})
+	assert.EqualError(t, err, "openrpcgen: no package name given")
+
+	_, err = Generate(document, Options{Package: "jsonrpc-gen"})
+	assert.EqualError(t, err, "openrpcgen: jsonrpc-gen is not a valid package name")
+
+	_, err = Generate(document, Options{Package: "venues"})
+	assert.EqualError(t, err, "openrpcgen: methods venue.get and venue_get are both named VenueGet")
+
+	document = &jsonrpc.OpenRPCDocument{
+		Components: &jsonrpc.OpenRPCComponents{Schemas: map[string]*jsonrpc.Schema{
+			"Client": {Type: "object"},
+		}},
+	}
+	_, err = Generate(document, Options{Package: "venues"})
+	assert.EqualError(t, err, "openrpcgen: schema Client clashes with the generated Client, set a prefix")
+
+	_, err = Generate(document, Options{Package: "venues", Prefix: "Venue"})
+	assert.Nil(t, err)
+}
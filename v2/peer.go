@@ -0,0 +1,344 @@
+package jsonrpc
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+)
+
+type peerContextKey struct{}
+
+// pendingCall is a call made by a Peer that is waiting for its response. The
+// hook, when set, is run as soon as the response is read from the connection,
+// before any later messages are routed.
+type pendingCall struct {
+	ch   chan *clientResponse
+	hook func(*clientResponse)
+}
+
+// PeerFromRequest returns the Peer a call arrived on, allowing a method to
+// send notifications or make calls back to the other side of the connection.
+// It returns nil for calls that did not arrive on a persistent connection.
+func PeerFromRequest(req *http.Request) *Peer {
+	if req == nil {
+		return nil
+	}
+	return PeerFromContext(req.Context())
+}
+
+// PeerFromContext returns the Peer stored in the context, if any.
+func PeerFromContext(ctx context.Context) *Peer {
+	peer, _ := ctx.Value(peerContextKey{}).(*Peer)
+	return peer
+}
+
+// Peer is one end of a persistent connection over which both sides may make
+// calls and serve them.
+//
+// Incoming calls are served by the Peer's Dispatcher, and incoming responses
+// are matched by ID to the calls made with Call. A Peer without a Dispatcher
+// answers every incoming call with a method not found error.
+//
+// MaxQueuedEvents is the number of events each subscription made with
+// Subscribe holds while waiting for its channel to be read, 1024 when zero.
+type Peer struct {
+	MaxQueuedEvents int
+	conn            messageConn
+	dispatcher      Dispatcher
+	req             *http.Request
+	pending         map[string]*pendingCall
+	hooks           map[*Call]func()
+	subs            map[string]*Subscription
+	clientSubs      map[string]*ClientSubscription
+	id              int
+	mtx             *sync.Mutex
+	done            chan struct{}
+	err             error
+}
+
+// newPeer returns a Peer for the connection. The http.Request is handed to the
+// dispatcher for every incoming call, with the Peer added to its context.
+func newPeer(conn messageConn, dispatcher Dispatcher, req *http.Request) *Peer {
+	if dispatcher == nil {
+		dispatcher = NewMapDispatcher()
+	}
+	peer := &Peer{
+		conn:       conn,
+		dispatcher: dispatcher,
+		pending:    make(map[string]*pendingCall),
+		hooks:      make(map[*Call]func()),
+		subs:       make(map[string]*Subscription),
+		clientSubs: make(map[string]*ClientSubscription),
+		id:         1,
+		mtx:        new(sync.Mutex),
+		done:       make(chan struct{}),
+	}
+	peer.req = req.WithContext(context.WithValue(req.Context(), peerContextKey{}, peer))
+	return peer
+}
+
+// NewPeer returns a pointer to a Peer exchanging messages over the stream
+// with the given framing, serving incoming calls with the dispatcher.
+func NewPeer(rwc io.ReadWriteCloser, framing Framing, dispatcher Dispatcher) *Peer {
+	peer := newPeer(newStreamConn(rwc, framing), dispatcher, newConnRequest(rwc))
+	go peer.serve()
+	return peer
+}
+
+// serve reads messages from the connection and routes them until reading
+// fails. Responses and subscription events are routed in the order they
+// arrive, while the calls in each message are dispatched in their own
+// goroutine so a slow call does not hold up the rest of the connection. serve
+// waits for calls in progress to finish before returning.
+func (peer *Peer) serve() error {
+	var wg sync.WaitGroup
+	var err error
+
+	for {
+		var data []byte
+		data, err = peer.conn.ReadMessage()
+		if err != nil {
+			break
+		}
+
+		messages, single, perr := parseMessages(data)
+		if perr != nil {
+			resp := &Response{
+				Version: "2.0",
+				Error: &Error{
+					Code:    CodeParseError,
+					Message: perr.Error(),
+				},
+			}
+			writeResponses(peer.conn, []*Response{resp}, true)
+			continue
+		}
+
+		var calls []*Call
+		for _, msg := range messages {
+			switch {
+			case msg == nil:
+				calls = append(calls, nil)
+			case !msg.isCall():
+				peer.deliver(msg.response())
+			case !peer.deliverEvent(msg):
+				calls = append(calls, msg.call())
+			}
+		}
+
+		if len(calls) == 0 {
+			continue
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			peer.dispatch(calls, single)
+		}()
+	}
+
+	peer.shutdown(err)
+	wg.Wait()
+	return err
+}
+
+// dispatch serves the calls from a single message and writes their responses
+// as a single message.
+func (peer *Peer) dispatch(calls []*Call, single bool) {
+	responses := dispatchCalls(peer.dispatcher, calls, peer.req, nil)
+
+	var replies []*Response
+	for i, call := range calls {
+		if call != nil && call.ID == nil {
+			continue
+		}
+		replies = append(replies, responses[i])
+	}
+
+	writeResponses(peer.conn, replies, single)
+
+	peer.mtx.Lock()
+	var hooks []func()
+	for _, call := range calls {
+		hook, ok := peer.hooks[call]
+		if ok {
+			hooks = append(hooks, hook)
+			delete(peer.hooks, call)
+		}
+	}
+	peer.mtx.Unlock()
+
+	for _, hook := range hooks {
+		hook()
+	}
+}
+
+// afterResponse arranges for fn to be run once the response to the call has
+// been written to the connection.
+func (peer *Peer) afterResponse(call *Call, fn func()) {
+	peer.mtx.Lock()
+	defer peer.mtx.Unlock()
+	peer.hooks[call.received()] = fn
+}
+
+func (peer *Peer) deliver(resp *clientResponse) {
+	id, ok := resp.ID.(string)
+	if !ok {
+		return
+	}
+
+	peer.mtx.Lock()
+	call, ok := peer.pending[id]
+	delete(peer.pending, id)
+	peer.mtx.Unlock()
+
+	if !ok {
+		return
+	}
+	if call.hook != nil {
+		call.hook(resp)
+	}
+	call.ch <- resp
+}
+
+// shutdown marks the peer as dead, failing any calls still waiting.
+func (peer *Peer) shutdown(err error) {
+	peer.mtx.Lock()
+	defer peer.mtx.Unlock()
+
+	select {
+	case <-peer.done:
+		return
+	default:
+	}
+
+	if err == nil || err == io.EOF {
+		err = ErrClosed
+	}
+	peer.err = err
+	close(peer.done)
+
+	for id, sub := range peer.subs {
+		sub.end()
+		delete(peer.subs, id)
+	}
+	for id, sub := range peer.clientSubs {
+		sub.end(err)
+		delete(peer.clientSubs, id)
+	}
+}
+
+// alive reports whether the underlying connection is still usable.
+func (peer *Peer) alive() bool {
+	select {
+	case <-peer.done:
+		return false
+	default:
+		return true
+	}
+}
+
+// Done returns a channel that is closed when the connection is lost.
+func (peer *Peer) Done() <-chan struct{} {
+	return peer.done
+}
+
+// Err returns the reason the connection was lost, or nil while it is alive.
+func (peer *Peer) Err() error {
+	peer.mtx.Lock()
+	defer peer.mtx.Unlock()
+	return peer.err
+}
+
+func (peer *Peer) nextID() string {
+	id := strconv.Itoa(peer.id)
+	peer.id = peer.id + 1
+	return id
+}
+
+// call sends a single call and waits for its response. The hook, when
+// given, is run as soon as the response arrives.
+func (peer *Peer) call(method string, params interface{}, hook func(*clientResponse)) (*clientResponse, error) {
+	pending := &pendingCall{
+		ch:   make(chan *clientResponse, 1),
+		hook: hook,
+	}
+
+	peer.mtx.Lock()
+	if !peer.alive() {
+		peer.mtx.Unlock()
+		return nil, peer.err
+	}
+	id := peer.nextID()
+	peer.pending[id] = pending
+	peer.mtx.Unlock()
+
+	data, err := DefaultJSONEngine.Marshal(&clientCall{
+		Version: "2.0",
+		ID:      id,
+		Method:  method,
+		Params:  params,
+	})
+	if err == nil {
+		err = peer.conn.WriteMessage(data)
+	}
+	if err != nil {
+		peer.mtx.Lock()
+		delete(peer.pending, id)
+		peer.mtx.Unlock()
+		return nil, err
+	}
+
+	select {
+	case resp := <-pending.ch:
+		return resp, nil
+	case <-peer.done:
+		select {
+		case resp := <-pending.ch:
+			return resp, nil
+		default:
+			return nil, fmt.Errorf("jsonrpc: connection lost waiting for response to %s: %v", method, peer.Err())
+		}
+	}
+}
+
+// Call makes a single JSONRPC call to the other side of the connection and
+// deserialises the result into the result argument.
+func (peer *Peer) Call(method string, params interface{}, result interface{}) error {
+	resp, err := peer.call(method, params, nil)
+	if err != nil {
+		return err
+	}
+
+	if resp.Error != nil {
+		return resp.Error
+	}
+	return DefaultJSONEngine.Unmarshal(resp.Result, result)
+}
+
+// Notify sends a call to the other side of the connection that expects no
+// response.
+func (peer *Peer) Notify(method string, params interface{}) error {
+	if !peer.alive() {
+		return peer.Err()
+	}
+
+	data, err := DefaultJSONEngine.Marshal(&clientNotification{
+		Version: "2.0",
+		Method:  method,
+		Params:  params,
+	})
+	if err != nil {
+		return err
+	}
+	return peer.conn.WriteMessage(data)
+}
+
+// Close closes the connection, failing any calls still waiting.
+func (peer *Peer) Close() error {
+	peer.shutdown(ErrClosed)
+	return peer.conn.Close()
+}
@@ -0,0 +1,181 @@
+package jsonrpc
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// pipeConn is one end of an in-memory messageConn.
+type pipeConn struct {
+	in     chan []byte
+	out    chan []byte
+	closed chan struct{}
+	once   *sync.Once
+}
+
+func newPipeConns() (*pipeConn, *pipeConn) {
+	a := make(chan []byte, 16)
+	b := make(chan []byte, 16)
+	closed := make(chan struct{})
+	once := new(sync.Once)
+	return &pipeConn{a, b, closed, once}, &pipeConn{b, a, closed, once}
+}
+
+func (conn *pipeConn) ReadMessage() ([]byte, error) {
+	select {
+	case data := <-conn.in:
+		return data, nil
+	case <-conn.closed:
+		return nil, io.EOF
+	}
+}
+
+func (conn *pipeConn) WriteMessage(data []byte) error {
+	select {
+	case conn.out <- data:
+		return nil
+	case <-conn.closed:
+		return io.ErrClosedPipe
+	}
+}
+
+func (conn *pipeConn) Close() error {
+	conn.once.Do(func() { close(conn.closed) })
+	return nil
+}
+
+func newAddDispatcher() *MapDispatcher {
+	dispatcher := NewMapDispatcher()
+	dispatcher.Register("add", func(resp *Response, call *Call, req *http.Request) {
+		var params []int
+		var result = 0
+		json.Unmarshal(call.Params, &params)
+		for _, n := range params {
+			result = result + n
+		}
+		resp.Result = result
+	})
+	return dispatcher
+}
+
+func TestPeer_serve(t *testing.T) {
+	server, client := newPipeConns()
+	go newPeer(server, newAddDispatcher(), newConnRequest(nil)).serve()
+	defer client.Close()
+
+	client.WriteMessage([]byte(`{"jsonrpc": "2.0", "id": "1", "method": "add", "params": [1, 2, 3]}`))
+	data, err := client.ReadMessage()
+	assert.Nil(t, err)
+	assert.JSONEq(t, `{"jsonrpc": "2.0", "id": "1", "result": 6}`, string(data))
+
+	client.WriteMessage([]byte(`[
+		{"jsonrpc": "2.0", "id": "2", "method": "add", "params": [1, 2]},
+		{"jsonrpc": "2.0", "method": "add", "params": [3, 4]}
+	]`))
+	data, err = client.ReadMessage()
+	assert.Nil(t, err)
+	assert.JSONEq(t, `[{"jsonrpc": "2.0", "id": "2", "result": 3}]`, string(data))
+
+	client.WriteMessage([]byte(`nope`))
+	data, err = client.ReadMessage()
+	assert.Nil(t, err)
+	var resp Response
+	json.Unmarshal(data, &resp)
+	assert.Equal(t, CodeParseError, resp.Error.Code)
+}
+
+func TestPeer_Call(t *testing.T) {
+	server, conn := newPipeConns()
+	go newPeer(server, newAddDispatcher(), newConnRequest(nil)).serve()
+
+	client := newPeer(conn, nil, newConnRequest(nil))
+	go client.serve()
+	defer client.Close()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			var result int
+			err := client.Call("add", []int{i, i}, &result)
+			assert.Nil(t, err)
+			assert.Equal(t, i*2, result)
+		}(i)
+	}
+	wg.Wait()
+
+	var result int
+	err := client.Call("subtract", []int{1, 2}, &result)
+	assert.Equal(t, CodeMethodNotFound, err.(*Error).Code)
+}
+
+func TestPeer_callback(t *testing.T) {
+	serverConn, clientConn := newPipeConns()
+
+	progress := make(chan string, 1)
+
+	clientDispatcher := NewMapDispatcher()
+	clientDispatcher.Register("progress", func(resp *Response, call *Call, req *http.Request) {
+		var message string
+		call.UnmarshalParams(&message)
+		progress <- message
+	})
+	clientDispatcher.Register("confirm", func(resp *Response, call *Call, req *http.Request) {
+		resp.Result = true
+	})
+
+	serverDispatcher := NewMapDispatcher()
+	serverDispatcher.Register("book", func(resp *Response, call *Call, req *http.Request) {
+		peer := PeerFromRequest(req)
+		peer.Notify("progress", "booking")
+
+		var confirmed bool
+		err := peer.Call("confirm", nil, &confirmed)
+		if err != nil {
+			resp.Error = &Error{Code: CodeInternalError, Message: err.Error()}
+			return
+		}
+		resp.Result = confirmed
+	})
+
+	server := newPeer(serverConn, serverDispatcher, newConnRequest(nil))
+	go server.serve()
+
+	client := newPeer(clientConn, clientDispatcher, newConnRequest(nil))
+	go client.serve()
+	defer client.Close()
+
+	var result bool
+	err := client.Call("book", nil, &result)
+	assert.Nil(t, err)
+	assert.True(t, result)
+	assert.Equal(t, "booking", <-progress)
+}
+
+func TestPeer_closed(t *testing.T) {
+	_, conn := newPipeConns()
+	client := newPeer(conn, nil, newConnRequest(nil))
+	go client.serve()
+	client.Close()
+
+	var result int
+	err := client.Call("add", []int{1, 2}, &result)
+	assert.Equal(t, ErrClosed, err)
+	assert.Equal(t, ErrClosed, client.Notify("add", []int{1, 2}))
+	assert.Equal(t, ErrClosed, client.Err())
+}
+
+func TestPeerFromRequest(t *testing.T) {
+	assert.Nil(t, PeerFromRequest(nil))
+	assert.Nil(t, PeerFromRequest(newConnRequest(nil)))
+
+	_, conn := newPipeConns()
+	peer := newPeer(conn, nil, newConnRequest(nil))
+	assert.Equal(t, peer, PeerFromRequest(peer.req))
+}
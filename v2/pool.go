@@ -0,0 +1,33 @@
+package jsonrpc
+
+import (
+	"bytes"
+	"sync"
+)
+
+// maxPooledBufferSize is the capacity above which buffers are left for the
+// garbage collector rather than returned to the pool, so one very large
+// request does not pin its memory for the life of the process.
+const maxPooledBufferSize = 1 << 20
+
+var bufferPool = sync.Pool{
+	New: func() interface{} {
+		return new(bytes.Buffer)
+	},
+}
+
+// getBuffer returns an empty buffer from the pool.
+func getBuffer() *bytes.Buffer {
+	buf := bufferPool.Get().(*bytes.Buffer)
+	buf.Reset()
+	return buf
+}
+
+// putBuffer returns a buffer to the pool. The buffer's contents must not be
+// used afterwards.
+func putBuffer(buf *bytes.Buffer) {
+	if buf.Cap() > maxPooledBufferSize {
+		return
+	}
+	bufferPool.Put(buf)
+}
@@ -0,0 +1,345 @@
+package jsonrpc
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// CodeRateLimited is the error code sent when a caller has exceeded its rate
+// limit. The error's data holds the number of seconds to wait before trying
+// again as retry_after.
+const CodeRateLimited int = -32004
+
+// ErrRateLimited is the message of errors sent to rate limited callers.
+var ErrRateLimited = errors.New("jsonrpc: rate limit exceeded")
+
+// RateLimit allows Rate calls per second on average, with bursts of up to
+// Burst calls. A Burst of zero allows bursts of one call.
+type RateLimit struct {
+	Rate  float64
+	Burst int
+}
+
+// validate returns an error if the limit can never refill a bucket.
+func (limit RateLimit) validate() error {
+	if !(limit.Rate > 0) || math.IsInf(limit.Rate, 1) {
+		return fmt.Errorf("jsonrpc: rate limit rate must be a positive number, not %v", limit.Rate)
+	}
+	if limit.Burst < 0 {
+		return fmt.Errorf("jsonrpc: rate limit burst must not be negative, not %d", limit.Burst)
+	}
+	return nil
+}
+
+// burst returns the size of a full bucket.
+func (limit RateLimit) burst() float64 {
+	if limit.Burst < 1 {
+		return 1
+	}
+	return float64(limit.Burst)
+}
+
+// A RateLimitStore holds the token buckets of a RateLimitDispatcher.
+//
+// Take removes a token from the bucket with the key, creating a full bucket
+// for the limit if there is none. It returns zero when a token was taken,
+// otherwise how long until one will be available.
+type RateLimitStore interface {
+	Take(key string, limit RateLimit) (time.Duration, error)
+}
+
+type tokenBucket struct {
+	tokens  float64
+	updated time.Time
+	limit   RateLimit
+}
+
+// MemoryRateLimitStore is a RateLimitStore holding token buckets in memory.
+// Buckets that have filled up again are removed from time to time.
+type MemoryRateLimitStore struct {
+	buckets map[string]*tokenBucket
+	takes   int
+	now     func() time.Time
+	mtx     *sync.Mutex
+}
+
+// memoryStoreSweep is the number of takes between removing full buckets.
+const memoryStoreSweep = 1024
+
+// NewMemoryRateLimitStore returns a pointer to an empty MemoryRateLimitStore.
+func NewMemoryRateLimitStore() *MemoryRateLimitStore {
+	store := &MemoryRateLimitStore{
+		buckets: make(map[string]*tokenBucket),
+		now:     time.Now,
+		mtx:     new(sync.Mutex),
+	}
+	return store
+}
+
+// Take implements the RateLimitStore interface.
+func (store *MemoryRateLimitStore) Take(key string, limit RateLimit) (time.Duration, error) {
+	err := limit.validate()
+	if err != nil {
+		return 0, err
+	}
+
+	store.mtx.Lock()
+	defer store.mtx.Unlock()
+
+	now := store.now()
+	burst := limit.burst()
+
+	store.takes++
+	if store.takes >= memoryStoreSweep {
+		store.takes = 0
+		store.sweep(now)
+	}
+
+	bucket, ok := store.buckets[key]
+	if !ok {
+		bucket = &tokenBucket{tokens: burst, updated: now, limit: limit}
+		store.buckets[key] = bucket
+	}
+
+	bucket.limit = limit
+	bucket.tokens = math.Min(burst, bucket.tokens+now.Sub(bucket.updated).Seconds()*limit.Rate)
+	bucket.updated = now
+
+	if bucket.tokens >= 1 {
+		bucket.tokens--
+		return 0, nil
+	}
+
+	wait := (1 - bucket.tokens) / limit.Rate
+	return time.Duration(wait * float64(time.Second)), nil
+}
+
+// sweep removes the buckets that will have refilled by now, as taking from
+// them again would create them full.
+func (store *MemoryRateLimitStore) sweep(now time.Time) {
+	for key, bucket := range store.buckets {
+		refill := bucket.limit.burst() / bucket.limit.Rate
+		if now.Sub(bucket.updated).Seconds() > refill {
+			delete(store.buckets, key)
+		}
+	}
+}
+
+// Len returns the number of buckets in the store.
+func (store *MemoryRateLimitStore) Len() int {
+	store.mtx.Lock()
+	defer store.mtx.Unlock()
+
+	return len(store.buckets)
+}
+
+// RateLimitKey returns the identity of the caller making a call, which is
+// given a token bucket of its own. An empty key is not rate limited.
+type RateLimitKey func(call *Call, req *http.Request) string
+
+// KeyByIP identifies callers by the IP address the request came from.
+func KeyByIP(call *Call, req *http.Request) string {
+	if req == nil {
+		return ""
+	}
+	host, _, err := net.SplitHostPort(req.RemoteAddr)
+	if err != nil {
+		return req.RemoteAddr
+	}
+	return host
+}
+
+// KeyByHeader identifies callers by a request header such as an API key,
+// falling back to their IP address when the header is missing.
+func KeyByHeader(name string) RateLimitKey {
+	return func(call *Call, req *http.Request) string {
+		if req != nil {
+			if value := req.Header.Get(name); value != "" {
+				return name + ":" + value
+			}
+		}
+		return KeyByIP(call, req)
+	}
+}
+
+// KeyByPrincipal identifies callers by the name of their Principal, falling
+// back to their IP address for unauthenticated requests.
+func KeyByPrincipal(call *Call, req *http.Request) string {
+	principal := PrincipalFromRequest(req)
+	if principal != nil {
+		return "principal:" + principal.Name
+	}
+	return KeyByIP(call, req)
+}
+
+// RateLimitDispatcher wraps a Dispatcher, limiting the rate at which each
+// caller may make calls with a token bucket per caller.
+//
+// Every call in a batch takes a token, and calls beyond the limit are
+// answered with a CodeRateLimited error without reaching the wrapped
+// Dispatcher. Callers are identified by Key, KeyByIP when nil. When PerMethod
+// is set each caller has a separate bucket for every method, and methods given
+// a limit with LimitMethod always have buckets of their own.
+type RateLimitDispatcher struct {
+	Dispatcher Dispatcher
+	Store      RateLimitStore
+	Key        RateLimitKey
+	Limit      RateLimit
+	PerMethod  bool
+	methods    map[string]RateLimit
+	mtx        *sync.Mutex
+}
+
+// NewRateLimitDispatcher returns a pointer to a RateLimitDispatcher wrapping
+// the dispatcher, holding its buckets in a MemoryRateLimitStore. It returns an
+// error if the limit has no positive Rate or a negative Burst.
+func NewRateLimitDispatcher(dispatcher Dispatcher, key RateLimitKey, limit RateLimit) (*RateLimitDispatcher, error) {
+	err := limit.validate()
+	if err != nil {
+		return nil, err
+	}
+
+	limiter := &RateLimitDispatcher{
+		Dispatcher: dispatcher,
+		Store:      NewMemoryRateLimitStore(),
+		Key:        key,
+		Limit:      limit,
+		methods:    make(map[string]RateLimit),
+		mtx:        new(sync.Mutex),
+	}
+	return limiter, nil
+}
+
+// LimitMethod sets a limit for calls to the method in place of Limit. It
+// returns an error if the limit has no positive Rate or a negative Burst.
+func (limiter *RateLimitDispatcher) LimitMethod(method string, limit RateLimit) error {
+	err := limit.validate()
+	if err != nil {
+		return err
+	}
+
+	limiter.mtx.Lock()
+	defer limiter.mtx.Unlock()
+
+	limiter.methods[method] = limit
+	return nil
+}
+
+// Methods lists the methods of the wrapped Dispatcher, if it can list them.
+func (limiter *RateLimitDispatcher) Methods() []string {
+	lister, ok := limiter.Dispatcher.(methodLister)
+	if !ok {
+		return nil
+	}
+	return lister.Methods()
+}
+
+// IsSafe reports whether the wrapped Dispatcher considers the method safe.
+// Implements the SafeDispatcher interface.
+func (limiter *RateLimitDispatcher) IsSafe(method string) bool {
+	safe, ok := limiter.Dispatcher.(SafeDispatcher)
+	return ok && safe.IsSafe(method)
+}
+
+// authorize applies the checks of the wrapped Dispatcher, if it has any.
+func (limiter *RateLimitDispatcher) authorize(call *Call, req *http.Request) *Error {
+	authorizer, ok := limiter.Dispatcher.(callAuthorizer)
+	if !ok {
+		return nil
+	}
+	return authorizer.authorize(call, req)
+}
+
+// Dispatch passes the call on to the wrapped Dispatcher if the caller has a
+// token left, otherwise answering it with a CodeRateLimited error.
+func (limiter *RateLimitDispatcher) Dispatch(resp *Response, call *Call, req *http.Request) {
+	keyFunc := limiter.Key
+	if keyFunc == nil {
+		keyFunc = KeyByIP
+	}
+
+	key := keyFunc(call, req)
+	if key == "" {
+		limiter.Dispatcher.Dispatch(resp, call, req)
+		return
+	}
+
+	limiter.mtx.Lock()
+	limit, ok := limiter.methods[call.Method]
+	limiter.mtx.Unlock()
+
+	if ok || limiter.PerMethod {
+		key += "\x00" + call.Method
+	}
+	if !ok {
+		limit = limiter.Limit
+	}
+
+	wait, err := limiter.Store.Take(key, limit)
+	if err != nil {
+		resp.Error = &Error{
+			Code:    CodeInternalError,
+			Message: err.Error(),
+		}
+		return
+	}
+
+	if wait > 0 {
+		rateLimited(resp, wait)
+		return
+	}
+
+	limiter.Dispatcher.Dispatch(resp, call, req)
+}
+
+// rateLimited answers the call with a CodeRateLimited error, also setting a
+// Retry-After header for GET requests.
+func rateLimited(resp *Response, wait time.Duration) {
+	seconds := int(math.Ceil(wait.Seconds()))
+
+	resp.Error = &Error{
+		Code:    CodeRateLimited,
+		Message: ErrRateLimited.Error(),
+		Data: map[string]interface{}{
+			"retry_after": seconds,
+		},
+	}
+
+	if resp.Header == nil {
+		resp.Header = make(http.Header)
+	}
+	resp.Header.Set("Retry-After", strconv.Itoa(seconds))
+}
+
+// RetryAfter returns how long to wait before retrying a call that failed with
+// a CodeRateLimited error. It reports false for any other error.
+func RetryAfter(err error) (time.Duration, bool) {
+	rpcErr, ok := err.(*Error)
+	if !ok || rpcErr.Code != CodeRateLimited {
+		return 0, false
+	}
+
+	data, ok := rpcErr.Data.(map[string]interface{})
+	if !ok {
+		return 0, false
+	}
+
+	switch value := data["retry_after"].(type) {
+	case float64:
+		return time.Duration(value * float64(time.Second)), true
+	case int:
+		return time.Duration(value) * time.Second, true
+	case json.Number:
+		seconds, err := value.Float64()
+		return time.Duration(seconds * float64(time.Second)), err == nil
+	default:
+		return 0, false
+	}
+}
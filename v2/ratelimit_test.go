@@ -0,0 +1,252 @@
+package jsonrpc
+
+import (
+	"encoding/json"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeClock struct {
+	now time.Time
+}
+
+func (clock *fakeClock) Now() time.Time {
+	return clock.now
+}
+
+func newFakeClockStore() (*MemoryRateLimitStore, *fakeClock) {
+	clock := &fakeClock{now: time.Unix(1500000000, 0)}
+	store := NewMemoryRateLimitStore()
+	store.now = clock.Now
+	return store, clock
+}
+
+func TestMemoryRateLimitStore_Take(t *testing.T) {
+	store, clock := newFakeClockStore()
+	limit := RateLimit{Rate: 2, Burst: 3}
+
+	for i := 0; i < 3; i++ {
+		wait, err := store.Take("a", limit)
+		assert.Nil(t, err)
+		assert.Equal(t, time.Duration(0), wait)
+	}
+
+	wait, err := store.Take("a", limit)
+	assert.Nil(t, err)
+	assert.Equal(t, 500*time.Millisecond, wait)
+
+	// other keys have buckets of their own
+	wait, _ = store.Take("b", limit)
+	assert.Equal(t, time.Duration(0), wait)
+
+	clock.now = clock.now.Add(500 * time.Millisecond)
+	wait, _ = store.Take("a", limit)
+	assert.Equal(t, time.Duration(0), wait)
+	wait, _ = store.Take("a", limit)
+	assert.Equal(t, 500*time.Millisecond, wait)
+
+	// buckets never fill beyond the burst
+	clock.now = clock.now.Add(time.Hour)
+	for i := 0; i < 3; i++ {
+		wait, _ = store.Take("a", limit)
+		assert.Equal(t, time.Duration(0), wait)
+	}
+	wait, _ = store.Take("a", limit)
+	assert.NotEqual(t, time.Duration(0), wait)
+}
+
+func TestMemoryRateLimitStore_sweep(t *testing.T) {
+	store, clock := newFakeClockStore()
+	limit := RateLimit{Rate: 1, Burst: 1}
+
+	store.Take("idle", limit)
+	clock.now = clock.now.Add(time.Minute)
+	for i := 0; i < memoryStoreSweep; i++ {
+		store.Take("busy", limit)
+	}
+	assert.Equal(t, 1, store.Len())
+
+	// a burst of zero refills as a burst of one
+	store, clock = newFakeClockStore()
+	limit = RateLimit{Rate: 1}
+
+	store.Take("recent", limit)
+	clock.now = clock.now.Add(500 * time.Millisecond)
+	for i := 0; i < memoryStoreSweep; i++ {
+		store.Take("busy", limit)
+	}
+	assert.Equal(t, 2, store.Len())
+	wait, err := store.Take("recent", limit)
+	assert.Nil(t, err)
+	assert.Equal(t, 500*time.Millisecond, wait)
+}
+
+func TestRateLimit_invalid(t *testing.T) {
+	store := NewMemoryRateLimitStore()
+	limiter, err := NewRateLimitDispatcher(NewMapDispatcher(), nil, RateLimit{Rate: 1})
+	assert.Nil(t, err)
+
+	tests := []RateLimit{
+		{},
+		{Rate: -1, Burst: 5},
+		{Rate: math.NaN(), Burst: 5},
+		{Rate: math.Inf(1), Burst: 5},
+		{Rate: 1, Burst: -1},
+	}
+
+	for _, limit := range tests {
+		_, err := NewRateLimitDispatcher(NewMapDispatcher(), nil, limit)
+		assert.NotNil(t, err)
+		assert.NotNil(t, limiter.LimitMethod("book", limit))
+		_, err = store.Take("a", limit)
+		assert.NotNil(t, err)
+	}
+	assert.Equal(t, 0, store.Len())
+}
+
+func TestKeys(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "/", nil)
+	req.RemoteAddr = "10.0.0.1:4321"
+	call := &Call{Method: "add"}
+
+	assert.Equal(t, "10.0.0.1", KeyByIP(call, req))
+	assert.Equal(t, "10.0.0.1", KeyByHeader("X-API-Key")(call, req))
+	assert.Equal(t, "10.0.0.1", KeyByPrincipal(call, req))
+	assert.Equal(t, "", KeyByIP(call, nil))
+
+	req.Header.Set("X-API-Key", "k3y")
+	assert.Equal(t, "X-API-Key:k3y", KeyByHeader("X-API-Key")(call, req))
+
+	req = req.WithContext(WithPrincipal(req.Context(), &Principal{Name: "sam"}))
+	assert.Equal(t, "principal:sam", KeyByPrincipal(call, req))
+}
+
+// requestFrom returns a request made from the address.
+func requestFrom(addr string) *http.Request {
+	req := httptest.NewRequest(http.MethodPost, "/", nil)
+	req.RemoteAddr = addr
+	return req
+}
+
+func TestRateLimitDispatcher_Dispatch(t *testing.T) {
+	var calls int32
+	limiter, err := NewRateLimitDispatcher(newNameDispatcher(&calls, "search", "book"), nil, RateLimit{Rate: 1, Burst: 2})
+	assert.Nil(t, err)
+
+	assert.Nil(t, dispatchCall(limiter, requestFrom("10.0.0.1:1"), "search", "").Error)
+	assert.Nil(t, dispatchCall(limiter, requestFrom("10.0.0.1:2"), "book", "").Error)
+
+	resp := dispatchCall(limiter, requestFrom("10.0.0.1:3"), "search", "")
+	assert.Equal(t, CodeRateLimited, resp.Error.Code)
+	assert.Equal(t, map[string]interface{}{"retry_after": 1}, resp.Error.Data)
+	assert.Equal(t, "1", resp.Header.Get("Retry-After"))
+
+	assert.Nil(t, dispatchCall(limiter, requestFrom("10.0.0.2:1"), "search", "").Error)
+	assert.Equal(t, int32(3), atomic.LoadInt32(&calls))
+}
+
+func TestRateLimitDispatcher_PerMethod(t *testing.T) {
+	var calls int32
+	limiter, err := NewRateLimitDispatcher(newNameDispatcher(&calls, "search", "book"), nil, RateLimit{Rate: 1, Burst: 1})
+	assert.Nil(t, err)
+	limiter.PerMethod = true
+
+	assert.Nil(t, dispatchCall(limiter, requestFrom("10.0.0.1:1"), "search", "").Error)
+	assert.Nil(t, dispatchCall(limiter, requestFrom("10.0.0.1:1"), "book", "").Error)
+	assert.NotNil(t, dispatchCall(limiter, requestFrom("10.0.0.1:1"), "search", "").Error)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&calls))
+}
+
+func TestRateLimitDispatcher_LimitMethod(t *testing.T) {
+	var calls int32
+	limiter, err := NewRateLimitDispatcher(newNameDispatcher(&calls, "search", "book"), nil, RateLimit{Rate: 1, Burst: 5})
+	assert.Nil(t, err)
+	assert.Nil(t, limiter.LimitMethod("book", RateLimit{Rate: 0.1, Burst: 1}))
+
+	assert.Nil(t, dispatchCall(limiter, requestFrom("10.0.0.1:1"), "book", "").Error)
+
+	resp := dispatchCall(limiter, requestFrom("10.0.0.1:1"), "book", "")
+	assert.Equal(t, CodeRateLimited, resp.Error.Code)
+	wait, ok := RetryAfter(resp.Error)
+	assert.True(t, ok)
+	assert.Equal(t, 10*time.Second, wait)
+
+	// the method's bucket is separate from the caller's
+	for i := 0; i < 5; i++ {
+		assert.Nil(t, dispatchCall(limiter, requestFrom("10.0.0.1:1"), "search", "").Error)
+	}
+	assert.NotNil(t, dispatchCall(limiter, requestFrom("10.0.0.1:1"), "search", "").Error)
+}
+
+func TestRateLimitDispatcher_batch(t *testing.T) {
+	var calls int32
+	limiter, err := NewRateLimitDispatcher(newNameDispatcher(&calls, "search", "book"), KeyByHeader("X-API-Key"), RateLimit{Rate: 0.5, Burst: 2})
+	assert.Nil(t, err)
+	server := httptest.NewServer(&Handler{Dispatcher: limiter})
+	defer server.Close()
+
+	req, _ := http.NewRequest(http.MethodPost, server.URL, strings.NewReader(`[
+		{"jsonrpc": "2.0", "id": "1", "method": "search"},
+		{"jsonrpc": "2.0", "id": "2", "method": "search"},
+		{"jsonrpc": "2.0", "id": "3", "method": "search"}
+	]`))
+	req.Header.Set("X-API-Key", "integrator")
+
+	response, err := http.DefaultClient.Do(req)
+	if !assert.Nil(t, err) {
+		t.FailNow()
+	}
+	defer response.Body.Close()
+
+	var responses []Response
+	assert.Nil(t, json.NewDecoder(response.Body).Decode(&responses))
+
+	limited := 0
+	for _, resp := range responses {
+		if resp.Error != nil {
+			assert.Equal(t, CodeRateLimited, resp.Error.Code)
+			wait, ok := RetryAfter(resp.Error)
+			assert.True(t, ok)
+			assert.Equal(t, 2*time.Second, wait)
+			limited++
+		}
+	}
+	assert.Equal(t, 1, limited)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&calls))
+}
+
+func TestRateLimitDispatcher_client(t *testing.T) {
+	var calls int32
+	limiter, err := NewRateLimitDispatcher(newNameDispatcher(&calls, "search", "book"), nil, RateLimit{Rate: 1, Burst: 1})
+	assert.Nil(t, err)
+	server := httptest.NewServer(&Handler{Dispatcher: limiter})
+	defer server.Close()
+
+	client := NewClient()
+	var result string
+	assert.Nil(t, client.Call(server.URL, "search", nil, &result))
+
+	err = client.Call(server.URL, "search", nil, &result)
+	wait, ok := RetryAfter(err)
+	assert.True(t, ok)
+	assert.Equal(t, time.Second, wait)
+}
+
+func TestRetryAfter(t *testing.T) {
+	_, ok := RetryAfter(&Error{Code: CodeInternalError})
+	assert.False(t, ok)
+
+	_, ok = RetryAfter(&Error{Code: CodeRateLimited})
+	assert.False(t, ok)
+
+	wait, ok := RetryAfter(&Error{Code: CodeRateLimited, Data: map[string]interface{}{"retry_after": json.Number("3")}})
+	assert.True(t, ok)
+	assert.Equal(t, 3*time.Second, wait)
+}
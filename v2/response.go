@@ -3,6 +3,7 @@ package jsonrpc
 import (
 	"encoding/json"
 	"fmt"
+	"net/http"
 )
 
 // Error codes generated by a JSONRPC Server
@@ -27,16 +28,33 @@ func (err *Error) Error() string {
 	return fmt.Sprintf("jsonrpc: %s (%d)", err.Message, err.Code)
 }
 
+// ToError converts an error returned by a method into an Error, passing on an
+// *Error as it is and giving others the CodeMiscError code.
+func ToError(err error) *Error {
+	rpcErr, ok := err.(*Error)
+	if ok {
+		return rpcErr
+	}
+	return &Error{
+		Code:    CodeMiscError,
+		Message: err.Error(),
+	}
+}
+
 // Response return the results of the method call to the client in the JSONRPC
 // format.
 //
 // It's fine for ID, Result, and Error to all be nil. For ID in particular, if
 // the Call specified an ID then the Response must include the original ID.
+//
+// Header holds http headers such as Cache-Control or ETag that are sent with
+// the response when the method was called with a GET request.
 type Response struct {
 	Version string      `json:"jsonrpc"`
 	ID      interface{} `json:"id"`
 	Result  interface{} `json:"result,omitempty"`
 	Error   *Error      `json:"error,omitempty"`
+	Header  http.Header `json:"-"`
 }
 
 // NewResponse creates a blank response for a specific call
@@ -1,6 +1,7 @@
 package jsonrpc
 
 import (
+	"errors"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -18,3 +19,10 @@ func TestError(t *testing.T) {
 	assert.Equal(t, errorStr, "jsonrpc: number1 is not a number (-32602)")
 
 }
+
+func TestToError(t *testing.T) {
+	err := &Error{Code: 404, Message: "venue not found"}
+	assert.Equal(t, err, ToError(err))
+
+	assert.Equal(t, &Error{Code: CodeMiscError, Message: "oops"}, ToError(errors.New("oops")))
+}
@@ -0,0 +1,191 @@
+package jsonrpc
+
+import (
+	"encoding"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// Schema is a JSON Schema, as used to describe params and results in an
+// OpenRPC document.
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Title                string             `json:"title,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	ContentEncoding      string             `json:"contentEncoding,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	Enum                 []interface{}      `json:"enum,omitempty"`
+
+	// order holds the names of the properties of a struct in field order
+	order []string
+}
+
+// schemaRefPrefix is the prefix of references to the schemas held in the
+// components of an OpenRPC document.
+const schemaRefPrefix = "#/components/schemas/"
+
+var (
+	typeOfTime          = reflect.TypeOf(time.Time{})
+	typeOfRawMessage    = reflect.TypeOf(json.RawMessage{})
+	typeOfMarshaler     = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+	typeOfTextMarshaler = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+)
+
+// schemaGenerator reflects JSON Schemas from Go types as encoding/json would
+// encode them. Named struct types are held once in schemas and referred to,
+// allowing recursive types.
+type schemaGenerator struct {
+	schemas map[string]*Schema
+	names   map[reflect.Type]string
+}
+
+func newSchemaGenerator() *schemaGenerator {
+	generator := &schemaGenerator{
+		schemas: make(map[string]*Schema),
+		names:   make(map[reflect.Type]string),
+	}
+	return generator
+}
+
+// schema returns the schema of values of the type.
+func (generator *schemaGenerator) schema(t reflect.Type) *Schema {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	switch {
+	case t == typeOfTime:
+		return &Schema{Type: "string", Format: "date-time"}
+	case t == typeOfRawMessage:
+		return &Schema{}
+	case t.Implements(typeOfMarshaler) || reflect.PtrTo(t).Implements(typeOfMarshaler):
+		// the encoding is up to the type
+		return &Schema{}
+	case t.Implements(typeOfTextMarshaler) || reflect.PtrTo(t).Implements(typeOfTextMarshaler):
+		return &Schema{Type: "string"}
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return &Schema{Type: "integer"}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number"}
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Kind() == reflect.Slice && t.Elem().Kind() == reflect.Uint8 {
+			return &Schema{Type: "string", ContentEncoding: "base64"}
+		}
+		return &Schema{Type: "array", Items: generator.schema(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: generator.schema(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return generator.structSchema(t)
+		}
+		return &Schema{Ref: schemaRefPrefix + generator.define(t)}
+	default:
+		// interfaces can hold anything
+		return &Schema{}
+	}
+}
+
+// define adds the schema of the named struct type to the schemas, returning
+// the name it is held under.
+func (generator *schemaGenerator) define(t reflect.Type) string {
+	name, ok := generator.names[t]
+	if ok {
+		return name
+	}
+
+	name = t.Name()
+	if _, taken := generator.schemas[name]; taken {
+		// a type with the same name from another package
+		pkg := t.PkgPath()
+		name = pkg[strings.LastIndexByte(pkg, '/')+1:] + "." + name
+	}
+
+	// the name is reserved before the fields are reflected so recursive types
+	// refer to it
+	generator.names[t] = name
+	generator.schemas[name] = &Schema{}
+	*generator.schemas[name] = *generator.structSchema(t)
+	return name
+}
+
+// structSchema returns the schema of an object with the struct's fields,
+// following the rules of encoding/json for tags and embedded structs. Fields
+// are required unless they are pointers or tagged omitempty.
+func (generator *schemaGenerator) structSchema(t reflect.Type) *Schema {
+	schema := &Schema{
+		Type:       "object",
+		Properties: make(map[string]*Schema),
+	}
+	generator.addFields(schema, t)
+	return schema
+}
+
+func (generator *schemaGenerator) addFields(schema *Schema, t reflect.Type) {
+	// fields of the outer struct hide those of embedded ones, so embedded
+	// structs are added last
+	var embedded []reflect.Type
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, options := tag, ""
+		if comma := strings.IndexByte(tag, ','); comma >= 0 {
+			name, options = tag[:comma], tag[comma:]
+		}
+
+		fieldType := field.Type
+		if field.Anonymous && name == "" {
+			for fieldType.Kind() == reflect.Ptr {
+				fieldType = fieldType.Elem()
+			}
+			if fieldType.Kind() == reflect.Struct {
+				embedded = append(embedded, fieldType)
+				continue
+			}
+		}
+
+		if field.PkgPath != "" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		if _, ok := schema.Properties[name]; ok {
+			continue
+		}
+
+		schema.order = append(schema.order, name)
+		if strings.Contains(options, ",string") {
+			schema.Properties[name] = &Schema{Type: "string"}
+		} else {
+			schema.Properties[name] = generator.schema(field.Type)
+		}
+
+		if !strings.Contains(options, ",omitempty") && field.Type.Kind() != reflect.Ptr {
+			schema.Required = append(schema.Required, name)
+		}
+	}
+
+	for _, fieldType := range embedded {
+		generator.addFields(schema, fieldType)
+	}
+}
@@ -0,0 +1,98 @@
+package jsonrpc
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type schemaAddress struct {
+	Street string `json:"street"`
+}
+
+type schemaBase struct {
+	ID      int    `json:"id"`
+	Name    string `json:"name"`
+	Created time.Time
+}
+
+type schemaCustomer struct {
+	schemaBase
+	Name     *string           `json:"name"`
+	Email    *string           `json:"email"`
+	Phone    string            `json:"phone,omitempty"`
+	Balance  int64             `json:"balance,string"`
+	Tags     []string          `json:"tags"`
+	Avatar   []byte            `json:"avatar"`
+	Address  schemaAddress     `json:"address"`
+	Previous []schemaAddress   `json:"previous"`
+	Extra    map[string]int    `json:"extra"`
+	Raw      json.RawMessage   `json:"raw"`
+	Any      interface{}       `json:"any"`
+	Referrer *schemaCustomer   `json:"referrer"`
+	Secret   string            `json:"-"`
+	Options  struct{ On bool } `json:"options"`
+	internal string
+}
+
+func TestSchemaGenerator(t *testing.T) {
+	generator := newSchemaGenerator()
+	schema := generator.schema(reflect.TypeOf(&schemaCustomer{}))
+	assert.Equal(t, "#/components/schemas/schemaCustomer", schema.Ref)
+
+	customer := generator.schemas["schemaCustomer"]
+	assert.Equal(t, "object", customer.Type)
+	assert.Equal(t, []string{
+		"name", "email", "phone", "balance", "tags", "avatar", "address",
+		"previous", "extra", "raw", "any", "referrer", "options",
+		"id", "Created",
+	}, customer.order)
+	assert.Equal(t, []string{
+		"balance", "tags", "avatar", "address", "previous",
+		"extra", "raw", "any", "options", "id", "Created",
+	}, customer.Required)
+
+	props := customer.Properties
+	assert.Equal(t, &Schema{Type: "string"}, props["name"])
+	assert.Equal(t, &Schema{Type: "string"}, props["email"])
+	assert.Equal(t, &Schema{Type: "string"}, props["balance"])
+	assert.Equal(t, &Schema{Type: "array", Items: &Schema{Type: "string"}}, props["tags"])
+	assert.Equal(t, &Schema{Type: "string", ContentEncoding: "base64"}, props["avatar"])
+	assert.Equal(t, &Schema{Ref: "#/components/schemas/schemaAddress"}, props["address"])
+	assert.Equal(t, &Schema{Type: "array", Items: &Schema{Ref: "#/components/schemas/schemaAddress"}}, props["previous"])
+	assert.Equal(t, &Schema{Type: "object", AdditionalProperties: &Schema{Type: "integer"}}, props["extra"])
+	assert.Equal(t, &Schema{}, props["raw"])
+	assert.Equal(t, &Schema{}, props["any"])
+	assert.Equal(t, &Schema{Ref: "#/components/schemas/schemaCustomer"}, props["referrer"])
+	assert.Equal(t, &Schema{Type: "integer"}, props["id"])
+	assert.Equal(t, &Schema{Type: "string", Format: "date-time"}, props["Created"])
+	assert.Equal(t, "object", props["options"].Type)
+	assert.Equal(t, &Schema{Type: "boolean"}, props["options"].Properties["On"])
+
+	// the outer struct's optional name hides the embedded one
+	assert.NotContains(t, customer.Required, "name")
+	assert.Len(t, props, 15)
+
+	assert.Equal(t, []string{"street"}, generator.schemas["schemaAddress"].Required)
+	assert.Len(t, generator.schemas, 2)
+}
+
+func TestSchemaGenerator_basic(t *testing.T) {
+	generator := newSchemaGenerator()
+	cases := map[interface{}]*Schema{
+		true:             {Type: "boolean"},
+		uint8(1):         {Type: "integer"},
+		1.5:              {Type: "number"},
+		"s":              {Type: "string"},
+		[2]int{}:         {Type: "array", Items: &Schema{Type: "integer"}},
+		time.Time{}:      {Type: "string", Format: "date-time"},
+		&Error{}:         {Ref: "#/components/schemas/Error"},
+		json.Number("1"): {Type: "string"},
+	}
+	for value, expected := range cases {
+		assert.Equal(t, expected, generator.schema(reflect.TypeOf(value)), "%T", value)
+	}
+}
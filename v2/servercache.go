@@ -0,0 +1,184 @@
+package jsonrpc
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// cachedResponse is the form in which responses are held by a
+// CachingDispatcher.
+type cachedResponse struct {
+	Result json.RawMessage `json:"result,omitempty"`
+	Error  *Error          `json:"error,omitempty"`
+	Header http.Header     `json:"header,omitempty"`
+}
+
+// CachingDispatcher wraps a Dispatcher, caching the results of pure methods
+// so identical calls are answered without calling the method again.
+//
+// Only methods added with CacheMethod are cached, keyed by method name and
+// params with object keys sorted. Error responses are never cached, and
+// concurrent identical calls share a single call to the method.
+type CachingDispatcher struct {
+	Dispatcher Dispatcher
+	cache      *LRUCache
+	methods    map[string]time.Duration
+	flights    *flightGroup
+	mtx        *sync.Mutex
+}
+
+// NewCachingDispatcher returns a pointer to a CachingDispatcher wrapping the
+// dispatcher and holding at most size results.
+func NewCachingDispatcher(dispatcher Dispatcher, size int) *CachingDispatcher {
+	caching := &CachingDispatcher{
+		Dispatcher: dispatcher,
+		cache:      NewLRUCache(size),
+		methods:    make(map[string]time.Duration),
+		flights:    newFlightGroup(),
+		mtx:        new(sync.Mutex),
+	}
+	return caching
+}
+
+// CacheMethod caches the results of the method for the given time to live. A
+// ttl of zero or less caches results until they are evicted or invalidated.
+func (dispatcher *CachingDispatcher) CacheMethod(method string, ttl time.Duration) {
+	dispatcher.mtx.Lock()
+	defer dispatcher.mtx.Unlock()
+
+	dispatcher.methods[method] = ttl
+}
+
+// Invalidate removes the cached result of the method for the given params.
+func (dispatcher *CachingDispatcher) Invalidate(method string, params interface{}) error {
+	canonical, err := canonicalParams(params)
+	if err != nil {
+		return err
+	}
+	dispatcher.cache.Delete(methodCacheKey(method, canonical))
+	return nil
+}
+
+// InvalidateMethod removes every cached result of the method.
+func (dispatcher *CachingDispatcher) InvalidateMethod(method string) {
+	dispatcher.cache.deletePrefix(method + "\x00")
+}
+
+// Purge removes every cached result.
+func (dispatcher *CachingDispatcher) Purge() {
+	dispatcher.cache.Purge()
+}
+
+// Methods lists the methods of the wrapped Dispatcher, if it can list them.
+func (dispatcher *CachingDispatcher) Methods() []string {
+	lister, ok := dispatcher.Dispatcher.(methodLister)
+	if !ok {
+		return nil
+	}
+	return lister.Methods()
+}
+
+// IsSafe reports whether the wrapped Dispatcher considers the method safe.
+// Implements the SafeDispatcher interface.
+func (dispatcher *CachingDispatcher) IsSafe(method string) bool {
+	safe, ok := dispatcher.Dispatcher.(SafeDispatcher)
+	return ok && safe.IsSafe(method)
+}
+
+// authorize applies the checks of the wrapped Dispatcher, if it has any, so
+// a CachingDispatcher can itself be wrapped or mounted.
+func (dispatcher *CachingDispatcher) authorize(call *Call, req *http.Request) *Error {
+	authorizer, ok := dispatcher.Dispatcher.(callAuthorizer)
+	if !ok {
+		return nil
+	}
+	return authorizer.authorize(call, req)
+}
+
+func methodCacheKey(method string, canonical []byte) string {
+	return method + "\x00" + string(canonical)
+}
+
+// Dispatch answers the call from the cache when possible, otherwise passing
+// it to the wrapped Dispatcher and caching a successful result.
+func (dispatcher *CachingDispatcher) Dispatch(resp *Response, call *Call, req *http.Request) {
+	dispatcher.mtx.Lock()
+	ttl, ok := dispatcher.methods[call.Method]
+	dispatcher.mtx.Unlock()
+
+	if !ok {
+		dispatcher.Dispatcher.Dispatch(resp, call, req)
+		return
+	}
+
+	// cached results must not reach callers the method would refuse
+	denied := dispatcher.authorize(call, req)
+	if denied != nil {
+		resp.Error = denied
+		return
+	}
+
+	canonical, err := canonicalJSON(call.Params)
+	if err != nil {
+		// invalid params are left for the method to reject
+		dispatcher.Dispatcher.Dispatch(resp, call, req)
+		return
+	}
+
+	key := methodCacheKey(call.Method, canonical)
+	data, ok := dispatcher.cache.Get(key)
+	if !ok {
+		data, err = dispatcher.flights.do(key, func() ([]byte, error) {
+			return dispatcher.dispatchAndCache(key, ttl, call, req)
+		})
+	}
+
+	var cached cachedResponse
+	if err == nil {
+		err = DefaultJSONEngine.Unmarshal(data, &cached)
+	}
+	if err != nil {
+		resp.Error = &Error{
+			Code:    CodeInternalError,
+			Message: err.Error(),
+		}
+		return
+	}
+
+	if len(cached.Result) > 0 {
+		resp.Result = cached.Result
+	}
+	resp.Error = cached.Error
+	resp.Header = cached.Header
+}
+
+// dispatchAndCache calls the method, caching the encoded response unless it
+// is an error.
+func (dispatcher *CachingDispatcher) dispatchAndCache(key string, ttl time.Duration, call *Call, req *http.Request) ([]byte, error) {
+	resp := NewResponse(call)
+	dispatcher.Dispatcher.Dispatch(resp, call, req)
+
+	cached := cachedResponse{
+		Error:  resp.Error,
+		Header: resp.Header,
+	}
+	if resp.Result != nil {
+		result, err := DefaultJSONEngine.Marshal(resp.Result)
+		if err != nil {
+			return nil, err
+		}
+		cached.Result = result
+	}
+
+	data, err := DefaultJSONEngine.Marshal(&cached)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.Error == nil {
+		dispatcher.cache.Set(key, data, ttl)
+	}
+	return data, nil
+}
@@ -0,0 +1,172 @@
+package jsonrpc
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newCountingDispatcher(calls *int32) *MapDispatcher {
+	dispatcher := NewMapDispatcher()
+	dispatcher.RegisterSafe("venue", func(resp *Response, call *Call, req *http.Request) {
+		atomic.AddInt32(calls, 1)
+		var params map[string]int
+		call.UnmarshalParams(&params)
+		if params["id"] == 0 {
+			resp.Error = &Error{Code: CodeInvalidParameters, Message: "missing id"}
+			return
+		}
+		resp.Header = http.Header{"Etag": []string{`"v1"`}}
+		resp.Result = map[string]int{"id": params["id"]}
+	})
+	dispatcher.Register("book", func(resp *Response, call *Call, req *http.Request) {
+		atomic.AddInt32(calls, 1)
+		resp.Result = true
+	})
+	return dispatcher
+}
+
+func TestCachingDispatcher(t *testing.T) {
+	var calls int32
+	dispatcher := NewCachingDispatcher(newCountingDispatcher(&calls), 10)
+	dispatcher.CacheMethod("venue", time.Minute)
+
+	resp := dispatchCall(dispatcher, nil, "venue", `{"id": 1, "lang": 2}`)
+	assert.Nil(t, resp.Error)
+	assert.Equal(t, `{"id":1}`, string(resp.Result.(json.RawMessage)))
+	assert.Equal(t, `"v1"`, resp.Header.Get("ETag"))
+
+	// same params with a different key order and spacing
+	resp = dispatchCall(dispatcher, nil, "venue", `{"lang":2,"id":1}`)
+	assert.Nil(t, resp.Error)
+	assert.Equal(t, `{"id":1}`, string(resp.Result.(json.RawMessage)))
+	assert.Equal(t, `"v1"`, resp.Header.Get("ETag"))
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+
+	dispatchCall(dispatcher, nil, "venue", `{"id": 2}`)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&calls))
+
+	// uncached methods always reach the method
+	dispatchCall(dispatcher, nil, "book", `[]`)
+	dispatchCall(dispatcher, nil, "book", `[]`)
+	assert.Equal(t, int32(4), atomic.LoadInt32(&calls))
+}
+
+func TestCachingDispatcher_errors(t *testing.T) {
+	var calls int32
+	dispatcher := NewCachingDispatcher(newCountingDispatcher(&calls), 10)
+	dispatcher.CacheMethod("venue", time.Minute)
+
+	resp := dispatchCall(dispatcher, nil, "venue", `{}`)
+	assert.Equal(t, CodeInvalidParameters, resp.Error.Code)
+	resp = dispatchCall(dispatcher, nil, "venue", `{}`)
+	assert.Equal(t, CodeInvalidParameters, resp.Error.Code)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&calls))
+}
+
+func TestCachingDispatcher_expiry(t *testing.T) {
+	var calls int32
+	dispatcher := NewCachingDispatcher(newCountingDispatcher(&calls), 10)
+	dispatcher.CacheMethod("venue", time.Millisecond)
+
+	dispatchCall(dispatcher, nil, "venue", `{"id": 1}`)
+	time.Sleep(5 * time.Millisecond)
+	dispatchCall(dispatcher, nil, "venue", `{"id": 1}`)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&calls))
+}
+
+func TestCachingDispatcher_invalidate(t *testing.T) {
+	var calls int32
+	dispatcher := NewCachingDispatcher(newCountingDispatcher(&calls), 10)
+	dispatcher.CacheMethod("venue", 0)
+
+	dispatchCall(dispatcher, nil, "venue", `{"id": 1}`)
+	dispatchCall(dispatcher, nil, "venue", `{"id": 2}`)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&calls))
+
+	assert.Nil(t, dispatcher.Invalidate("venue", map[string]int{"id": 1}))
+	dispatchCall(dispatcher, nil, "venue", `{"id": 1}`)
+	dispatchCall(dispatcher, nil, "venue", `{"id": 2}`)
+	assert.Equal(t, int32(3), atomic.LoadInt32(&calls))
+
+	dispatcher.InvalidateMethod("venue")
+	dispatchCall(dispatcher, nil, "venue", `{"id": 1}`)
+	dispatchCall(dispatcher, nil, "venue", `{"id": 2}`)
+	assert.Equal(t, int32(5), atomic.LoadInt32(&calls))
+
+	dispatcher.Purge()
+	dispatchCall(dispatcher, nil, "venue", `{"id": 1}`)
+	assert.Equal(t, int32(6), atomic.LoadInt32(&calls))
+}
+
+func TestCachingDispatcher_eviction(t *testing.T) {
+	var calls int32
+	dispatcher := NewCachingDispatcher(newCountingDispatcher(&calls), 1)
+	dispatcher.CacheMethod("venue", 0)
+
+	dispatchCall(dispatcher, nil, "venue", `{"id": 1}`)
+	dispatchCall(dispatcher, nil, "venue", `{"id": 2}`)
+	dispatchCall(dispatcher, nil, "venue", `{"id": 1}`)
+	assert.Equal(t, int32(3), atomic.LoadInt32(&calls))
+}
+
+func TestCachingDispatcher_concurrent(t *testing.T) {
+	var calls int32
+	release := make(chan struct{})
+	inner := NewMapDispatcher()
+	inner.Register("slow", func(resp *Response, call *Call, req *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		<-release
+		resp.Result = "done"
+	})
+
+	dispatcher := NewCachingDispatcher(inner, 10)
+	dispatcher.CacheMethod("slow", time.Minute)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			resp := dispatchCall(dispatcher, nil, "slow", `null`)
+			assert.Equal(t, `"done"`, string(resp.Result.(json.RawMessage)))
+		}()
+	}
+
+	time.Sleep(10 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+}
+
+func TestCachingDispatcher_over_http(t *testing.T) {
+	var calls int32
+	dispatcher := NewCachingDispatcher(newCountingDispatcher(&calls), 10)
+	dispatcher.CacheMethod("venue", time.Minute)
+
+	server := httptest.NewServer(&Handler{Dispatcher: dispatcher})
+	defer server.Close()
+
+	client := NewClient()
+	for i := 0; i < 2; i++ {
+		var result map[string]int
+		err := client.Call(server.URL, "venue", map[string]int{"id": 3}, &result)
+		assert.Nil(t, err)
+		assert.Equal(t, 3, result["id"])
+	}
+
+	// the wrapped dispatcher's safe methods can still be called with GET
+	var result map[string]int
+	err := client.Get(server.URL, "venue", map[string]int{"id": 3}, &result)
+	assert.Nil(t, err)
+	assert.Equal(t, 3, result["id"])
+
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+}
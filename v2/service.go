@@ -0,0 +1,264 @@
+package jsonrpc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+	"unicode"
+)
+
+// NameFunc returns the JSONRPC method name for a method of a service
+// registered with RegisterService.
+type NameFunc func(service string, method string) string
+
+// ServiceMethodName names methods as the service's type name and the method
+// name separated by a dot, such as "Venue.Get". It is the default NameFunc.
+func ServiceMethodName(service string, method string) string {
+	return service + "." + method
+}
+
+// SnakeCaseName names methods in snake case, such as "venue_service.get_seats".
+func SnakeCaseName(service string, method string) string {
+	return snakeCase(service) + "." + snakeCase(method)
+}
+
+// MethodName names methods with the method name alone, for use when the
+// service is registered with a Group.
+func MethodName(service string, method string) string {
+	return method
+}
+
+// snakeCase converts a Go identifier to snake case, keeping acronyms such as
+// "HTTPServer" together as "http_server".
+func snakeCase(name string) string {
+	runes := []rune(name)
+	var builder strings.Builder
+
+	for i, r := range runes {
+		if unicode.IsUpper(r) {
+			if i > 0 {
+				previous := runes[i-1]
+				nextIsLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+				if unicode.IsLower(previous) || unicode.IsDigit(previous) || (unicode.IsUpper(previous) && nextIsLower) {
+					builder.WriteByte('_')
+				}
+			}
+			r = unicode.ToLower(r)
+		}
+		builder.WriteRune(r)
+	}
+	return builder.String()
+}
+
+var (
+	typeOfError   = reflect.TypeOf((*error)(nil)).Elem()
+	typeOfContext = reflect.TypeOf((*context.Context)(nil)).Elem()
+)
+
+// serviceMethod calls a method of a service from its JSONRPC params.
+type serviceMethod struct {
+	receiver reflect.Value
+	method   reflect.Method
+	context  bool
+	params   reflect.Type
+	reply    reflect.Type
+}
+
+// newServiceMethod returns the serviceMethod for a method with one of the
+// signatures accepted by RegisterService, or false if it has another.
+func newServiceMethod(receiver reflect.Value, method reflect.Method) (*serviceMethod, bool) {
+	mtype := method.Type
+	sm := &serviceMethod{
+		receiver: receiver,
+		method:   method,
+	}
+
+	// the receiver is the first argument
+	in := make([]reflect.Type, 0, mtype.NumIn()-1)
+	for i := 1; i < mtype.NumIn(); i++ {
+		in = append(in, mtype.In(i))
+	}
+
+	if len(in) > 0 && in[0] == typeOfContext {
+		sm.context = true
+		in = in[1:]
+	}
+
+	switch {
+	case mtype.NumOut() == 1 && mtype.Out(0) == typeOfError && len(in) == 2 && in[1].Kind() == reflect.Ptr:
+		// func(params P, reply *R) error
+		sm.params = in[0]
+		sm.reply = in[1].Elem()
+	case mtype.NumOut() == 2 && mtype.Out(1) == typeOfError && len(in) <= 1:
+		// func(params P) (R, error) or func() (R, error)
+		if len(in) == 1 {
+			sm.params = in[0]
+		}
+	default:
+		return nil, false
+	}
+
+	if mtype.IsVariadic() {
+		return nil, false
+	}
+	return sm, true
+}
+
+// call decodes the params and calls the method, writing its result or error
+// to the response.
+func (sm *serviceMethod) call(resp *Response, call *Call, req *http.Request) {
+	args := []reflect.Value{sm.receiver}
+
+	if sm.context {
+		ctx := context.Background()
+		if req != nil {
+			ctx = req.Context()
+		}
+		args = append(args, reflect.ValueOf(ctx))
+	}
+
+	if sm.params != nil {
+		params, err := sm.decodeParams(call.Params)
+		if err != nil {
+			resp.Error = &Error{
+				Code:    CodeInvalidParameters,
+				Message: err.Error(),
+			}
+			return
+		}
+		args = append(args, params)
+	}
+
+	var reply reflect.Value
+	if sm.reply != nil {
+		reply = reflect.New(sm.reply)
+		args = append(args, reply)
+	}
+
+	out := sm.method.Func.Call(args)
+
+	errValue := out[len(out)-1]
+	if !errValue.IsNil() {
+		resp.Error = ToError(errValue.Interface().(error))
+		return
+	}
+
+	if sm.reply != nil {
+		resp.Result = reply.Interface()
+	} else {
+		resp.Result = out[0].Interface()
+	}
+}
+
+// info returns the MethodInfo holding the types of the method's params and
+// result.
+func (sm *serviceMethod) info() *MethodInfo {
+	info := &MethodInfo{}
+	if sm.params != nil {
+		info.Params = reflect.Zero(sm.params).Interface()
+	}
+	if sm.reply != nil {
+		info.Result = reflect.Zero(sm.reply).Interface()
+	} else {
+		info.Result = reflect.Zero(sm.method.Type.Out(0)).Interface()
+	}
+	return info
+}
+
+// decodeParams decodes the call's params into a new value of the method's
+// params type, or failing that into the only element of an array of params so
+// they can also be given by position.
+func (sm *serviceMethod) decodeParams(data json.RawMessage) (reflect.Value, error) {
+	params := reflect.New(sm.params)
+
+	if len(data) == 0 || string(data) == "null" {
+		return params.Elem(), nil
+	}
+
+	err := DefaultJSONEngine.Unmarshal(data, params.Interface())
+	if err == nil || !isBatch(data) {
+		return params.Elem(), err
+	}
+
+	var positional []json.RawMessage
+	if DefaultJSONEngine.Unmarshal(data, &positional) != nil || len(positional) != 1 {
+		return params.Elem(), err
+	}
+
+	params = reflect.New(sm.params)
+	err = DefaultJSONEngine.Unmarshal(positional[0], params.Interface())
+	return params.Elem(), err
+}
+
+// RegisterService registers each exported method of the service that has
+// one of the signatures
+//
+//	func (s *Service) Method(params P) (R, error)
+//	func (s *Service) Method(params P, reply *R) error
+//	func (s *Service) Method() (R, error)
+//
+// any of which may take a context.Context holding the request's context as
+// its first argument. Methods are named by naming, ServiceMethodName when
+// nil, and other methods are ignored.
+//
+// The params of a call are decoded into P, or into the only element of an
+// array of params. Errors returned as an *Error are sent as they are, other
+// errors are sent with the CodeMiscError code.
+func (dispatcher *MapDispatcher) RegisterService(service interface{}, naming NameFunc) error {
+	if naming == nil {
+		naming = ServiceMethodName
+	}
+
+	receiver := reflect.ValueOf(service)
+	if !receiver.IsValid() || (receiver.Kind() == reflect.Ptr && receiver.IsNil()) {
+		return errors.New("jsonrpc: unable to register a nil service")
+	}
+	serviceType := receiver.Type()
+	serviceName := reflect.Indirect(receiver).Type().Name()
+	if serviceName == "" {
+		return fmt.Errorf("jsonrpc: unable to register service of type %s as it has no name", serviceType)
+	}
+
+	methods := make(map[string]*serviceMethod)
+	for i := 0; i < serviceType.NumMethod(); i++ {
+		method := serviceType.Method(i)
+		if method.PkgPath != "" {
+			continue
+		}
+
+		sm, ok := newServiceMethod(receiver, method)
+		if !ok {
+			continue
+		}
+		methods[naming(serviceName, method.Name)] = sm
+	}
+
+	if len(methods) == 0 {
+		return fmt.Errorf("jsonrpc: unable to register service %s as it has no suitable methods", serviceName)
+	}
+
+	dispatcher.mtx.Lock()
+	defer dispatcher.mtx.Unlock()
+
+	for name := range methods {
+		_, ok := dispatcher.methods[name]
+		if ok {
+			return fmt.Errorf("jsonrpc: unable to register method with name %s as it already exists", name)
+		}
+	}
+
+	for name, sm := range methods {
+		dispatcher.methods[name] = sm.call
+		dispatcher.infos[name] = sm.info()
+	}
+	return nil
+}
+
+// RegisterService adds the methods of the service to the DefaultDispatcher
+func RegisterService(service interface{}, naming NameFunc) error {
+	return DefaultDispatcher.RegisterService(service, naming)
+}
@@ -0,0 +1,156 @@
+package jsonrpc
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type VenueParams struct {
+	ID int `json:"id"`
+}
+
+type Venue struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+type VenueService struct {
+	venues map[int]*Venue
+}
+
+func newVenueService() *VenueService {
+	return &VenueService{venues: map[int]*Venue{
+		1: {ID: 1, Name: "Apollo"},
+		2: {ID: 2, Name: "Lyceum"},
+	}}
+}
+
+func (service *VenueService) Get(params VenueParams) (*Venue, error) {
+	venue, ok := service.venues[params.ID]
+	if !ok {
+		return nil, &Error{Code: 404, Message: "venue not found"}
+	}
+	return venue, nil
+}
+
+func (service *VenueService) Count() (int, error) {
+	return len(service.venues), nil
+}
+
+func (service *VenueService) Rename(params Venue, reply *Venue) error {
+	venue, ok := service.venues[params.ID]
+	if !ok {
+		return errors.New("no such venue")
+	}
+	venue.Name = params.Name
+	*reply = *venue
+	return nil
+}
+
+func (service *VenueService) ListHTTPVenues(ctx context.Context, ids []int) ([]string, error) {
+	principal := PrincipalFromContext(ctx)
+	names := []string{principal.Name}
+	for _, id := range ids {
+		names = append(names, service.venues[id].Name)
+	}
+	return names, nil
+}
+
+// Unsuitable signatures are ignored
+func (service *VenueService) Close()                           {}
+func (service *VenueService) Lookup(id int) *Venue             { return nil }
+func (service *VenueService) Many(a int, b int) (int, error)   { return 0, nil }
+func (service *VenueService) Variadic(ids ...int) (int, error) { return 0, nil }
+func (service *VenueService) unexported() (int, error)         { return 0, nil }
+
+func TestMapDispatcher_RegisterService(t *testing.T) {
+	dispatcher := NewMapDispatcher()
+	assert.Nil(t, dispatcher.RegisterService(newVenueService(), nil))
+
+	assert.Equal(t, []string{
+		"VenueService.Count",
+		"VenueService.Get",
+		"VenueService.ListHTTPVenues",
+		"VenueService.Rename",
+	}, dispatcher.Methods())
+
+	resp := dispatchCall(dispatcher, requestAs(&Principal{Name: "sam"}), "VenueService.Get", `{"id": 1}`)
+	assert.Nil(t, resp.Error)
+	assert.Equal(t, &Venue{ID: 1, Name: "Apollo"}, resp.Result)
+
+	// a single params object may be given by position
+	resp = dispatchCall(dispatcher, requestAs(&Principal{Name: "sam"}), "VenueService.Get", `[{"id": 2}]`)
+	assert.Equal(t, &Venue{ID: 2, Name: "Lyceum"}, resp.Result)
+
+	resp = dispatchCall(dispatcher, requestAs(&Principal{Name: "sam"}), "VenueService.Count", "")
+	assert.Equal(t, 2, resp.Result)
+
+	resp = dispatchCall(dispatcher, requestAs(&Principal{Name: "sam"}), "VenueService.Rename", `{"id": 1, "name": "Apollo Victoria"}`)
+	assert.Nil(t, resp.Error)
+	assert.Equal(t, &Venue{ID: 1, Name: "Apollo Victoria"}, resp.Result)
+
+	resp = dispatchCall(dispatcher, requestAs(&Principal{Name: "sam"}), "VenueService.ListHTTPVenues", `[1, 2]`)
+	assert.Equal(t, []string{"sam", "Apollo Victoria", "Lyceum"}, resp.Result)
+}
+
+func TestMapDispatcher_RegisterService_errors(t *testing.T) {
+	dispatcher := NewMapDispatcher()
+	assert.Nil(t, dispatcher.RegisterService(newVenueService(), nil))
+
+	resp := dispatchCall(dispatcher, requestAs(&Principal{Name: "sam"}), "VenueService.Get", `{"id": 3}`)
+	assert.Equal(t, &Error{Code: 404, Message: "venue not found"}, resp.Error)
+
+	resp = dispatchCall(dispatcher, requestAs(&Principal{Name: "sam"}), "VenueService.Rename", `{"id": 3}`)
+	assert.Equal(t, CodeMiscError, resp.Error.Code)
+	assert.Equal(t, "no such venue", resp.Error.Message)
+
+	resp = dispatchCall(dispatcher, requestAs(&Principal{Name: "sam"}), "VenueService.Get", `{"id": "one"}`)
+	assert.Equal(t, CodeInvalidParameters, resp.Error.Code)
+
+	resp = dispatchCall(dispatcher, requestAs(&Principal{Name: "sam"}), "VenueService.Get", `[{"id": 1}, {"id": 2}]`)
+	assert.Equal(t, CodeInvalidParameters, resp.Error.Code)
+
+	// registering the same methods again fails without registering any
+	err := dispatcher.RegisterService(newVenueService(), nil)
+	assert.NotNil(t, err)
+	assert.Len(t, dispatcher.Methods(), 4)
+
+	assert.NotNil(t, dispatcher.RegisterService(struct{}{}, nil))
+	assert.NotNil(t, dispatcher.RegisterService(&struct{}{}, nil))
+	assert.NotNil(t, dispatcher.RegisterService(nil, nil))
+	assert.NotNil(t, dispatcher.RegisterService((*VenueService)(nil), nil))
+}
+
+func TestMapDispatcher_RegisterService_naming(t *testing.T) {
+	dispatcher := NewMapDispatcher()
+	assert.Nil(t, dispatcher.RegisterService(newVenueService(), SnakeCaseName))
+	assert.Equal(t, []string{
+		"venue_service.count",
+		"venue_service.get",
+		"venue_service.list_http_venues",
+		"venue_service.rename",
+	}, dispatcher.Methods())
+
+	dispatcher = NewMapDispatcher()
+	assert.Nil(t, dispatcher.Group("venue").RegisterService(newVenueService(), MethodName))
+	resp := dispatchCall(dispatcher, requestAs(&Principal{Name: "sam"}), "venue.Get", `{"id": 1}`)
+	assert.Equal(t, "Apollo", resp.Result.(*Venue).Name)
+}
+
+func TestSnakeCase(t *testing.T) {
+	cases := map[string]string{
+		"Get":            "get",
+		"GetVenue":       "get_venue",
+		"HTTPServer":     "http_server",
+		"ListHTTPVenues": "list_http_venues",
+		"Venue2Seats":    "venue2_seats",
+		"ID":             "id",
+		"already_snake":  "already_snake",
+	}
+	for name, expected := range cases {
+		assert.Equal(t, expected, snakeCase(name), name)
+	}
+}
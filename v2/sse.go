@@ -0,0 +1,201 @@
+package jsonrpc
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+const (
+	// EventStreamContentType is the content type of Server-Sent Event
+	// streams. Clients asking for it in the Accept header receive any
+	// notifications sent by the method before its response.
+	EventStreamContentType = "text/event-stream"
+
+	eventNotification = "notification"
+	eventResponse     = "response"
+)
+
+// eventStream writes Server-Sent Events to a http response.
+type eventStream struct {
+	w       io.Writer
+	flusher http.Flusher
+	engine  JSONEngine
+	mtx     *sync.Mutex
+}
+
+func (stream *eventStream) writeEvent(event string, v interface{}) error {
+	data, err := stream.engine.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	stream.mtx.Lock()
+	defer stream.mtx.Unlock()
+
+	_, err = fmt.Fprintf(stream.w, "event: %s\ndata: %s\n\n", event, data)
+	if err != nil {
+		return err
+	}
+	stream.flusher.Flush()
+	return nil
+}
+
+// Notify sends a notification event. Implements the Notifier interface.
+func (stream *eventStream) Notify(method string, params interface{}) error {
+	return stream.writeEvent(eventNotification, &clientNotification{
+		Version: "2.0",
+		Method:  method,
+		Params:  params,
+	})
+}
+
+// serveEventStream dispatches the calls, streaming any notifications sent by
+// the methods to the client as events followed by a final response event.
+func (handler *Handler) serveEventStream(w http.ResponseWriter, flusher http.Flusher, r *http.Request, calls []*Call, single bool) {
+	w.Header().Set("Content-Type", EventStreamContentType)
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	stream := &eventStream{
+		w:       w,
+		flusher: flusher,
+		engine:  handler.JSON.engine(),
+		mtx:     new(sync.Mutex),
+	}
+
+	responses := dispatchCalls(handler.Dispatcher, calls, withNotifier(r, stream), nil)
+
+	var err error
+	if single {
+		err = stream.writeEvent(eventResponse, responses[0])
+	} else {
+		err = stream.writeEvent(eventResponse, responses)
+	}
+
+	if err != nil {
+		stream.writeEvent(eventResponse, &Response{
+			Version: "2.0",
+			Error: &Error{
+				Code:    CodeInternalError,
+				Message: err.Error(),
+			},
+		})
+	}
+}
+
+// readEvents reads Server-Sent Events from r, calling fn with the type and
+// data of each event.
+func readEvents(r io.Reader, fn func(event string, data []byte) error) error {
+	reader := bufio.NewReader(r)
+
+	var event string
+	var data bytes.Buffer
+
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil && (err != io.EOF || line == "") {
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+		line = strings.TrimRight(line, "\r\n")
+
+		switch {
+		case line == "":
+			if data.Len() > 0 {
+				ferr := fn(event, data.Bytes())
+				if ferr != nil {
+					return ferr
+				}
+			}
+			event = ""
+			data.Reset()
+		case strings.HasPrefix(line, ":"):
+			// comment
+		case strings.HasPrefix(line, "event:"):
+			event = strings.TrimSpace(strings.TrimPrefix(line, "event:"))
+		case strings.HasPrefix(line, "data:"):
+			if data.Len() > 0 {
+				data.WriteByte('\n')
+			}
+			data.WriteString(strings.TrimPrefix(strings.TrimPrefix(line, "data:"), " "))
+		}
+	}
+}
+
+// doStream executes a request asking for an event stream, calling notify
+// with each notification sent before the response.
+func (client *Client) doStream(req *http.Request, result interface{}, notify func(*Call)) error {
+	req.Header.Set("Accept", EventStreamContentType)
+
+	rawresp, err := client.send(req)
+	if err != nil {
+		return err
+	}
+	defer rawresp.Body.Close()
+
+	if !hasContentType(rawresp, EventStreamContentType) {
+		// the server does not stream, so the body is a regular response
+		body, err := client.readBody(rawresp)
+		if err != nil {
+			return err
+		}
+		return decodeResponse(body, result, client.JSON)
+	}
+
+	body, err := responseBody(rawresp)
+	if err != nil {
+		return err
+	}
+
+	var received bool
+	err = readEvents(body, func(event string, data []byte) error {
+		switch event {
+		case eventNotification:
+			var call Call
+			err := client.JSON.Unmarshal(data, &call)
+			if err != nil {
+				return err
+			}
+			if notify != nil {
+				notify(&call)
+			}
+		case eventResponse:
+			received = true
+			return decodeResponse(data, result, client.JSON)
+		}
+		return nil
+	})
+
+	if err != nil {
+		return err
+	}
+	if !received {
+		return fmt.Errorf("jsonrpc: event stream ended without a response")
+	}
+	return nil
+}
+
+// DoStream executes a http.Request asking the server to stream any
+// notifications sent by the method before its response. notify is called
+// with each notification as it arrives.
+func (client *Client) DoStream(req *http.Request, result interface{}, notify func(*Call)) error {
+	return client.doStream(req, result, notify)
+}
+
+// CallStream makes a single JSONRPC request to the server, calling notify
+// with any notifications sent by the method before its response.
+func (client *Client) CallStream(url string, method string, params interface{}, result interface{}, notify func(*Call)) error {
+	req, err := client.newRequest(url, method, params)
+	if err != nil {
+		return err
+	}
+	return client.doStream(req, result, notify)
+}
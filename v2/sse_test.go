@@ -0,0 +1,153 @@
+package jsonrpc
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newProgressDispatcher() *MapDispatcher {
+	dispatcher := NewMapDispatcher()
+	dispatcher.Register("count", func(resp *Response, call *Call, req *http.Request) {
+		var n int
+		call.UnmarshalParams(&n)
+		for i := 1; i <= n; i++ {
+			Notify(req, "progress", i)
+		}
+		resp.Result = n
+	})
+	return dispatcher
+}
+
+func TestServeHTTP_event_stream(t *testing.T) {
+	server := httptest.NewServer(&Handler{Dispatcher: newProgressDispatcher()})
+	defer server.Close()
+
+	buf := bytes.NewBufferString(`{"jsonrpc": "2.0", "id": "1", "method": "count", "params": 2}`)
+	req, _ := http.NewRequest(http.MethodPost, server.URL, buf)
+	req.Header.Set("Accept", "text/event-stream, application/json")
+
+	response, err := http.DefaultClient.Do(req)
+	if !assert.Nil(t, err) {
+		t.FailNow()
+	}
+	defer response.Body.Close()
+
+	assert.Equal(t, EventStreamContentType, response.Header.Get("Content-Type"))
+
+	var events []string
+	var data []string
+	err = readEvents(response.Body, func(event string, d []byte) error {
+		events = append(events, event)
+		data = append(data, string(d))
+		return nil
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"notification", "notification", "response"}, events)
+	assert.JSONEq(t, `{"jsonrpc": "2.0", "method": "progress", "params": 1}`, data[0])
+	assert.JSONEq(t, `{"jsonrpc": "2.0", "method": "progress", "params": 2}`, data[1])
+	assert.JSONEq(t, `{"jsonrpc": "2.0", "id": "1", "result": 2}`, data[2])
+}
+
+func TestReadEvents(t *testing.T) {
+	stream := ": comment\r\nevent: response\r\ndata: {\"a\":\r\ndata: 1}\r\n\r\ndata: [1]\n\n"
+
+	var events []string
+	var data []string
+	err := readEvents(strings.NewReader(stream), func(event string, d []byte) error {
+		events = append(events, event)
+		data = append(data, string(d))
+		return nil
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"response", ""}, events)
+	assert.Equal(t, []string{"{\"a\":\n1}", "[1]"}, data)
+}
+
+func TestClient_CallStream(t *testing.T) {
+	server := httptest.NewServer(&Handler{Dispatcher: newProgressDispatcher()})
+	defer server.Close()
+
+	var progress []int
+	var result int
+	err := NewClient().CallStream(server.URL, "count", 3, &result, func(call *Call) {
+		assert.Equal(t, "progress", call.Method)
+		var n int
+		call.UnmarshalParams(&n)
+		progress = append(progress, n)
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, 3, result)
+	assert.Equal(t, []int{1, 2, 3}, progress)
+}
+
+// countingEngine counts the values marshalled and unmarshalled by the
+// engine it wraps.
+type countingEngine struct {
+	JSONEngine
+	marshals   int32
+	unmarshals int32
+}
+
+func (engine *countingEngine) Marshal(v interface{}) ([]byte, error) {
+	atomic.AddInt32(&engine.marshals, 1)
+	return engine.JSONEngine.Marshal(v)
+}
+
+func (engine *countingEngine) Unmarshal(data []byte, v interface{}) error {
+	atomic.AddInt32(&engine.unmarshals, 1)
+	return engine.JSONEngine.Unmarshal(data, v)
+}
+
+func TestClient_CallStream_engine(t *testing.T) {
+	serverEngine := &countingEngine{JSONEngine: StandardJSONEngine}
+	server := httptest.NewServer(&Handler{
+		Dispatcher: newProgressDispatcher(),
+		JSON:       &JSONCodec{Engine: serverEngine},
+	})
+	defer server.Close()
+
+	clientEngine := &countingEngine{JSONEngine: StandardJSONEngine}
+	client := NewClient()
+	client.JSON = &JSONCodec{Engine: clientEngine}
+
+	var progress int
+	var result int
+	err := client.CallStream(server.URL, "count", 2, &result, func(call *Call) {
+		progress++
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, 2, result)
+	assert.Equal(t, 2, progress)
+
+	// the two notifications and the response go through both engines
+	assert.Equal(t, int32(3), atomic.LoadInt32(&serverEngine.marshals))
+	assert.GreaterOrEqual(t, atomic.LoadInt32(&clientEngine.unmarshals), int32(3))
+}
+
+func TestClient_CallStream_with_error(t *testing.T) {
+	server := httptest.NewServer(&Handler{Dispatcher: newProgressDispatcher()})
+	defer server.Close()
+
+	var result int
+	err := NewClient().CallStream(server.URL, "missing", 3, &result, nil)
+	assert.Equal(t, CodeMethodNotFound, err.(*Error).Code)
+}
+
+func TestClient_CallStream_without_streaming_server(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"jsonrpc": "2.0", "id": "1", "result": 3}`))
+	}))
+	defer server.Close()
+
+	var result int
+	err := NewClient().CallStream(server.URL, "count", 3, &result, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, 3, result)
+}
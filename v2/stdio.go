@@ -0,0 +1,91 @@
+package jsonrpc
+
+import (
+	"io"
+	"os"
+	"os/exec"
+)
+
+// ioConn joins a separate reader and writer into an io.ReadWriteCloser.
+// Closing it closes whichever of the two are closers.
+type ioConn struct {
+	io.Reader
+	io.Writer
+}
+
+func (conn ioConn) Close() error {
+	var err error
+	if closer, ok := conn.Writer.(io.Closer); ok {
+		err = closer.Close()
+	}
+	if closer, ok := conn.Reader.(io.Closer); ok {
+		cerr := closer.Close()
+		if err == nil {
+			err = cerr
+		}
+	}
+	return err
+}
+
+// ServeIO serves calls read from r and writes their responses to w until r
+// is exhausted.
+func (server *StreamServer) ServeIO(r io.Reader, w io.Writer) error {
+	return server.ServeConn(ioConn{r, w})
+}
+
+// ServeStdio serves calls from the dispatcher on the process's stdin and
+// stdout using Content-Length framing, as expected of Language Server
+// Protocol style tools.
+func ServeStdio(dispatcher Dispatcher) error {
+	server := &StreamServer{
+		Dispatcher: dispatcher,
+		Framing:    HeaderFraming,
+	}
+	return server.ServeIO(os.Stdin, os.Stdout)
+}
+
+// NewIOClient returns a pointer to a StreamClient that writes calls to w and
+// reads responses from r.
+func NewIOClient(r io.Reader, w io.Writer, framing Framing) *StreamClient {
+	return NewStreamClient(ioConn{r, w}, framing)
+}
+
+// CommandClient makes JSONRPC calls to a subprocess over its stdin and
+// stdout.
+type CommandClient struct {
+	*StreamClient
+	Cmd *exec.Cmd
+}
+
+// StartCommand starts the command and returns a CommandClient talking to it
+// over its stdin and stdout. The command's Stdin and Stdout must not already
+// be set.
+func StartCommand(cmd *exec.Cmd, framing Framing) (*CommandClient, error) {
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		stdin.Close()
+		return nil, err
+	}
+
+	err = cmd.Start()
+	if err != nil {
+		return nil, err
+	}
+
+	client := &CommandClient{
+		StreamClient: NewStreamClient(ioConn{stdout, stdin}, framing),
+		Cmd:          cmd,
+	}
+	return client, nil
+}
+
+// Close closes the subprocess's stdin and waits for it to exit.
+func (client *CommandClient) Close() error {
+	client.StreamClient.Close()
+	return client.Cmd.Wait()
+}
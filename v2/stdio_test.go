@@ -0,0 +1,75 @@
+package jsonrpc
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestHelperProcess is not a real test. It is started as a subprocess by the
+// tests below to serve calls on stdin and stdout.
+func TestHelperProcess(t *testing.T) {
+	if os.Getenv("GO_WANT_HELPER_PROCESS") != "1" {
+		return
+	}
+	ServeStdio(newAddDispatcher())
+	os.Exit(0)
+}
+
+func TestStreamServer_ServeIO(t *testing.T) {
+	var in bytes.Buffer
+	var out bytes.Buffer
+
+	body := `{"jsonrpc": "2.0", "id": "1", "method": "add", "params": [4]}`
+	fmt.Fprintf(&in, "Content-Length: %d\r\n\r\n%s", len(body), body)
+
+	server := &StreamServer{Dispatcher: newAddDispatcher(), Framing: HeaderFraming}
+	err := server.ServeIO(&in, &out)
+	assert.Nil(t, err)
+
+	conn := newStreamConn(nopCloser{&out}, HeaderFraming)
+	data, err := conn.ReadMessage()
+	assert.Nil(t, err)
+	assert.JSONEq(t, `{"jsonrpc": "2.0", "id": "1", "result": 4}`, string(data))
+}
+
+func TestNewIOClient(t *testing.T) {
+	serverIn, clientOut := io.Pipe()
+	clientIn, serverOut := io.Pipe()
+
+	server := &StreamServer{Dispatcher: newAddDispatcher(), Framing: HeaderFraming}
+	go server.ServeIO(serverIn, serverOut)
+
+	client := NewIOClient(clientIn, clientOut, HeaderFraming)
+	defer client.Close()
+
+	var result int
+	err := client.Call("add", []int{1, 2, 3}, &result)
+	assert.Nil(t, err)
+	assert.Equal(t, 6, result)
+}
+
+func TestStartCommand(t *testing.T) {
+	cmd := exec.Command(os.Args[0], "-test.run=TestHelperProcess")
+	cmd.Env = append(os.Environ(), "GO_WANT_HELPER_PROCESS=1")
+
+	client, err := StartCommand(cmd, HeaderFraming)
+	if !assert.Nil(t, err) {
+		t.FailNow()
+	}
+
+	var result int
+	err = client.Call("add", []int{1, 2, 3}, &result)
+	assert.Nil(t, err)
+	assert.Equal(t, 6, result)
+
+	err = client.Call("subtract", []int{1, 2, 3}, &result)
+	assert.Equal(t, CodeMethodNotFound, err.(*Error).Code)
+
+	assert.Nil(t, client.Close())
+}
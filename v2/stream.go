@@ -0,0 +1,246 @@
+package jsonrpc
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Framing controls how messages are delimited on a stream connection.
+type Framing int
+
+const (
+	// NewlineFraming separates messages with a newline, each message being a
+	// single line of JSON.
+	NewlineFraming Framing = iota
+	// HeaderFraming precedes each message with a Content-Length header as
+	// used by the Language Server Protocol.
+	HeaderFraming
+)
+
+// defaultMaxMessageSize is the largest message in bytes read from a stream
+// connection when no other limit is set.
+const defaultMaxMessageSize = 10 << 20
+
+// ErrMessageTooLarge is returned when a message read from a stream connection
+// is larger than its maximum size.
+var ErrMessageTooLarge = errors.New("jsonrpc: message too large")
+
+// streamConn adapts a byte stream to a messageConn using the given framing.
+type streamConn struct {
+	rwc     io.ReadWriteCloser
+	reader  *bufio.Reader
+	framing Framing
+	maxSize int
+	mtx     *sync.Mutex
+}
+
+func newStreamConn(rwc io.ReadWriteCloser, framing Framing) *streamConn {
+	return &streamConn{
+		rwc:     rwc,
+		reader:  bufio.NewReader(rwc),
+		framing: framing,
+		maxSize: defaultMaxMessageSize,
+		mtx:     new(sync.Mutex),
+	}
+}
+
+func (conn *streamConn) ReadMessage() ([]byte, error) {
+	if conn.framing == HeaderFraming {
+		return conn.readHeaderFramed()
+	}
+
+	for {
+		line, err := conn.readLine()
+		line = bytes.TrimSpace(line)
+		if len(line) > 0 {
+			return line, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+// readLine reads up to and including the next newline, failing with
+// ErrMessageTooLarge once the line is longer than the maximum message size.
+func (conn *streamConn) readLine() ([]byte, error) {
+	var line []byte
+	for {
+		chunk, err := conn.reader.ReadSlice('\n')
+		if len(line)+len(chunk) > conn.maxSize+1 {
+			return nil, ErrMessageTooLarge
+		}
+		line = append(line, chunk...)
+		if err != bufio.ErrBufferFull {
+			return line, err
+		}
+	}
+}
+
+func (conn *streamConn) readHeaderFramed() ([]byte, error) {
+	length := -1
+
+	for {
+		// header lines are held to the same limit as messages
+		raw, err := conn.readLine()
+		if err != nil {
+			return nil, err
+		}
+		line := strings.TrimSpace(string(raw))
+		if line == "" {
+			if length < 0 {
+				// tolerate blank lines between messages
+				continue
+			}
+			break
+		}
+
+		colon := strings.IndexByte(line, ':')
+		if colon < 0 {
+			return nil, fmt.Errorf("jsonrpc: invalid header line %q", line)
+		}
+
+		name := strings.TrimSpace(line[:colon])
+		if !strings.EqualFold(name, "Content-Length") {
+			continue
+		}
+
+		length, err = strconv.Atoi(strings.TrimSpace(line[colon+1:]))
+		if err != nil || length < 0 {
+			return nil, fmt.Errorf("jsonrpc: invalid Content-Length header %q", line)
+		}
+		if length > conn.maxSize {
+			return nil, ErrMessageTooLarge
+		}
+	}
+
+	data := make([]byte, length)
+	_, err := io.ReadFull(conn.reader, data)
+	if err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+func (conn *streamConn) WriteMessage(data []byte) error {
+	conn.mtx.Lock()
+	defer conn.mtx.Unlock()
+
+	var buf bytes.Buffer
+	if conn.framing == HeaderFraming {
+		fmt.Fprintf(&buf, "Content-Length: %d\r\n\r\n", len(data))
+		buf.Write(data)
+	} else {
+		buf.Write(data)
+		buf.WriteByte('\n')
+	}
+
+	_, err := conn.rwc.Write(buf.Bytes())
+	return err
+}
+
+func (conn *streamConn) Close() error {
+	return conn.rwc.Close()
+}
+
+// newConnRequest returns the http.Request handed to the Dispatcher for calls
+// arriving on a connection that did not start life as a http request.
+func newConnRequest(rwc io.ReadWriteCloser) *http.Request {
+	req, _ := http.NewRequest(http.MethodPost, "/", nil)
+	if conn, ok := rwc.(net.Conn); ok && conn.RemoteAddr() != nil {
+		req.RemoteAddr = conn.RemoteAddr().String()
+	}
+	return req
+}
+
+// StreamServer serves JSONRPC calls over stream connections such as TCP or
+// Unix domain sockets.
+//
+// Calls on a connection are handled concurrently and responses are written as
+// soon as they are ready, so they may arrive out of order. The http.Request
+// passed to the Dispatcher is synthesised for each connection and carries the
+// remote address when there is one. Methods can get the Peer for the
+// connection with PeerFromRequest to call back to the client.
+//
+// A connection sending a message or header line larger than MaxMessageSize
+// bytes, 10MB when zero, is closed.
+type StreamServer struct {
+	Dispatcher     Dispatcher
+	Framing        Framing
+	MaxMessageSize int
+}
+
+// Serve accepts connections on the listener and serves each of them in its
+// own goroutine. It returns when the listener fails.
+func (server *StreamServer) Serve(listener net.Listener) error {
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			return err
+		}
+		go server.ServeConn(conn)
+	}
+}
+
+// ServeConn serves calls on a single connection until it is closed.
+func (server *StreamServer) ServeConn(rwc io.ReadWriteCloser) error {
+	conn := newStreamConn(rwc, server.Framing)
+	if server.MaxMessageSize > 0 {
+		conn.maxSize = server.MaxMessageSize
+	}
+
+	peer := newPeer(conn, server.Dispatcher, newConnRequest(rwc))
+	defer peer.Close()
+
+	err := peer.serve()
+	if err == io.EOF {
+		return nil
+	}
+	return err
+}
+
+// ServeStream serves calls from the DefaultDispatcher on the listener.
+func ServeStream(listener net.Listener, framing Framing) error {
+	server := &StreamServer{
+		Dispatcher: DefaultDispatcher,
+		Framing:    framing,
+	}
+	return server.Serve(listener)
+}
+
+// StreamClient makes JSONRPC calls over a stream connection. Calls may be
+// made concurrently from many goroutines and are matched to their responses
+// by ID.
+//
+// A StreamClient is a Peer without a Dispatcher, use NewPeer when the server
+// needs to make calls to the client.
+type StreamClient struct {
+	*Peer
+}
+
+// NewStreamClient returns a pointer to a StreamClient making calls over the
+// given connection.
+func NewStreamClient(rwc io.ReadWriteCloser, framing Framing) *StreamClient {
+	client := &StreamClient{
+		Peer: NewPeer(rwc, framing, nil),
+	}
+	return client
+}
+
+// DialStream connects to the address on the named network, as with net.Dial,
+// and returns a StreamClient for the connection.
+func DialStream(network string, address string, framing Framing) (*StreamClient, error) {
+	conn, err := net.Dial(network, address)
+	if err != nil {
+		return nil, err
+	}
+	return NewStreamClient(conn, framing), nil
+}
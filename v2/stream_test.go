@@ -0,0 +1,184 @@
+package jsonrpc
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type nopCloser struct {
+	*bytes.Buffer
+}
+
+func (nopCloser) Close() error { return nil }
+
+func TestStreamConn_newline(t *testing.T) {
+	buf := nopCloser{new(bytes.Buffer)}
+	conn := newStreamConn(buf, NewlineFraming)
+
+	conn.WriteMessage([]byte(`{"a": 1}`))
+	conn.WriteMessage([]byte(`[1, 2]`))
+	assert.Equal(t, "{\"a\": 1}\n[1, 2]\n", buf.String())
+
+	data, err := conn.ReadMessage()
+	assert.Nil(t, err)
+	assert.Equal(t, `{"a": 1}`, string(data))
+
+	data, err = conn.ReadMessage()
+	assert.Nil(t, err)
+	assert.Equal(t, `[1, 2]`, string(data))
+}
+
+func TestStreamConn_header(t *testing.T) {
+	buf := nopCloser{new(bytes.Buffer)}
+	conn := newStreamConn(buf, HeaderFraming)
+
+	conn.WriteMessage([]byte("{\"a\":\n1}"))
+	assert.Equal(t, "Content-Length: 8\r\n\r\n{\"a\":\n1}", buf.String())
+
+	buf.WriteString("Content-Type: application/vscode-jsonrpc; charset=utf-8\r\ncontent-length: 2\r\n\r\n[]")
+
+	data, err := conn.ReadMessage()
+	assert.Nil(t, err)
+	assert.Equal(t, "{\"a\":\n1}", string(data))
+
+	data, err = conn.ReadMessage()
+	assert.Nil(t, err)
+	assert.Equal(t, "[]", string(data))
+}
+
+func TestStreamConn_header_invalid(t *testing.T) {
+	buf := nopCloser{bytes.NewBufferString("Content-Length: abc\r\n\r\n")}
+	conn := newStreamConn(buf, HeaderFraming)
+
+	_, err := conn.ReadMessage()
+	assert.NotNil(t, err)
+}
+
+func TestStreamConn_too_large(t *testing.T) {
+	buf := nopCloser{bytes.NewBufferString("Content-Length: 999999999999999\r\n\r\n")}
+	conn := newStreamConn(buf, HeaderFraming)
+
+	_, err := conn.ReadMessage()
+	assert.Equal(t, ErrMessageTooLarge, err)
+
+	buf = nopCloser{bytes.NewBufferString("Content-Length: -1\r\n\r\n")}
+	conn = newStreamConn(buf, HeaderFraming)
+
+	_, err = conn.ReadMessage()
+	assert.NotNil(t, err)
+
+	buf = nopCloser{bytes.NewBufferString("[1, 2]\n" + strings.Repeat(" ", 10000) + "[3]\n")}
+	conn = newStreamConn(buf, NewlineFraming)
+	conn.maxSize = 8
+
+	data, err := conn.ReadMessage()
+	assert.Nil(t, err)
+	assert.Equal(t, "[1, 2]", string(data))
+
+	_, err = conn.ReadMessage()
+	assert.Equal(t, ErrMessageTooLarge, err)
+}
+
+func TestStreamConn_header_too_large(t *testing.T) {
+	header := "X-Padding: " + strings.Repeat("a", 10000) + "\r\n"
+	buf := nopCloser{bytes.NewBufferString(header + "Content-Length: 2\r\n\r\n[]")}
+	conn := newStreamConn(buf, HeaderFraming)
+	conn.maxSize = 64
+
+	_, err := conn.ReadMessage()
+	assert.Equal(t, ErrMessageTooLarge, err)
+
+	// header lines within the limit are read as before
+	buf = nopCloser{bytes.NewBufferString("X-Padding: aaa\r\nContent-Length: 2\r\n\r\n[]")}
+	conn = newStreamConn(buf, HeaderFraming)
+	conn.maxSize = 64
+
+	data, err := conn.ReadMessage()
+	assert.Nil(t, err)
+	assert.Equal(t, "[]", string(data))
+}
+
+func TestStreamServer_MaxMessageSize(t *testing.T) {
+	serverConn, clientConn := net.Pipe()
+	defer clientConn.Close()
+
+	server := &StreamServer{Dispatcher: newAddDispatcher(), Framing: HeaderFraming, MaxMessageSize: 16}
+	errs := make(chan error, 1)
+	go func() {
+		errs <- server.ServeConn(serverConn)
+	}()
+
+	clientConn.Write([]byte("Content-Length: 17\r\n\r\n"))
+	assert.Equal(t, ErrMessageTooLarge, <-errs)
+}
+
+func testStreamClient(t *testing.T, framing Framing) {
+	serverConn, clientConn := net.Pipe()
+
+	server := &StreamServer{Dispatcher: newAddDispatcher(), Framing: framing}
+	go server.ServeConn(serverConn)
+
+	client := NewStreamClient(clientConn, framing)
+	defer client.Close()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			var result int
+			err := client.Call("add", []int{i, 2}, &result)
+			assert.Nil(t, err)
+			assert.Equal(t, i+2, result)
+		}(i)
+	}
+	wg.Wait()
+
+	var result int
+	err := client.Call("divide", []int{1, 2}, &result)
+	assert.Equal(t, CodeMethodNotFound, err.(*Error).Code)
+}
+
+func TestStreamClient_newline(t *testing.T) {
+	testStreamClient(t, NewlineFraming)
+}
+
+func TestStreamClient_header(t *testing.T) {
+	testStreamClient(t, HeaderFraming)
+}
+
+func TestStreamServer_Serve_unix(t *testing.T) {
+	dir, err := ioutil.TempDir("", "jsonrpc")
+	if !assert.Nil(t, err) {
+		t.FailNow()
+	}
+	defer os.RemoveAll(dir)
+
+	listener, err := net.Listen("unix", filepath.Join(dir, "rpc.sock"))
+	if !assert.Nil(t, err) {
+		t.FailNow()
+	}
+	defer listener.Close()
+
+	server := &StreamServer{Dispatcher: newAddDispatcher()}
+	go server.Serve(listener)
+
+	client, err := DialStream("unix", listener.Addr().String(), NewlineFraming)
+	if !assert.Nil(t, err) {
+		t.FailNow()
+	}
+	defer client.Close()
+
+	var result int
+	err = client.Call("add", []int{1, 2, 3}, &result)
+	assert.Nil(t, err)
+	assert.Equal(t, 6, result)
+}
@@ -0,0 +1,394 @@
+package jsonrpc
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"sync"
+)
+
+const (
+	// SubscriptionMethod is the method name of the notifications carrying
+	// subscription events.
+	SubscriptionMethod = "rpc.subscription"
+	// UnsubscribeMethod is the method called by clients to end a
+	// subscription. It takes the subscription ID as its only parameter.
+	UnsubscribeMethod = "rpc.unsubscribe"
+)
+
+// ErrUnsubscribed is returned when sending events on a subscription that has
+// ended.
+var ErrUnsubscribed = errors.New("jsonrpc: subscription has ended")
+
+// ErrSubscriptionOverflow ends a client subscription whose events arrive
+// faster than its channel is read, once Peer.MaxQueuedEvents are waiting.
+var ErrSubscriptionOverflow = errors.New("jsonrpc: subscription events overflowed the queue")
+
+// defaultMaxQueuedEvents is the number of events a client subscription holds
+// when the Peer sets no other limit.
+const defaultMaxQueuedEvents = 1024
+
+// subscriptionEvent holds the params of a SubscriptionMethod notification.
+type subscriptionEvent struct {
+	Subscription string          `json:"subscription"`
+	Result       json.RawMessage `json:"result"`
+}
+
+type serverEvent struct {
+	Subscription string      `json:"subscription"`
+	Result       interface{} `json:"result"`
+}
+
+// SubscriptionFunc produces the events for a subscription. It is run in its
+// own goroutine once the subscription ID has been sent to the client and
+// should send events with Subscription.Notify until Subscription.Done is
+// closed. The subscription ends when the function returns.
+type SubscriptionFunc func(sub *Subscription, call *Call, req *http.Request)
+
+// Subscription is the server side of a subscription made over a persistent
+// connection.
+type Subscription struct {
+	ID    string
+	peer  *Peer
+	ready chan struct{}
+	done  chan struct{}
+	once  *sync.Once
+}
+
+func newSubscriptionID() string {
+	buf := make([]byte, 16)
+	rand.Read(buf)
+	return hex.EncodeToString(buf)
+}
+
+// Notify sends an event to the subscriber.
+func (sub *Subscription) Notify(event interface{}) error {
+	select {
+	case <-sub.done:
+		return ErrUnsubscribed
+	default:
+	}
+
+	return sub.peer.Notify(SubscriptionMethod, &serverEvent{
+		Subscription: sub.ID,
+		Result:       event,
+	})
+}
+
+// Done returns a channel that is closed when the client unsubscribes or the
+// connection is lost.
+func (sub *Subscription) Done() <-chan struct{} {
+	return sub.done
+}
+
+// Close ends the subscription from the server side.
+func (sub *Subscription) Close() {
+	sub.peer.mtx.Lock()
+	delete(sub.peer.subs, sub.ID)
+	sub.peer.mtx.Unlock()
+	sub.end()
+}
+
+func (sub *Subscription) end() {
+	sub.once.Do(func() { close(sub.done) })
+}
+
+// subscribe returns a Method that starts a subscription on the Peer the call
+// arrived on, responding with the subscription ID.
+func subscribe(fn SubscriptionFunc) Method {
+	return func(resp *Response, call *Call, req *http.Request) {
+		peer := PeerFromRequest(req)
+		if peer == nil {
+			resp.Error = &Error{
+				Code:    CodeInvalidRequest,
+				Message: fmt.Sprintf("jsonrpc: %s requires a persistent connection", call.Method),
+			}
+			return
+		}
+
+		sub := &Subscription{
+			ID:    newSubscriptionID(),
+			peer:  peer,
+			ready: make(chan struct{}),
+			done:  make(chan struct{}),
+			once:  new(sync.Once),
+		}
+
+		peer.mtx.Lock()
+		if !peer.alive() {
+			peer.mtx.Unlock()
+			resp.Error = &Error{
+				Code:    CodeInternalError,
+				Message: ErrClosed.Error(),
+			}
+			return
+		}
+		peer.subs[sub.ID] = sub
+		peer.mtx.Unlock()
+
+		peer.afterResponse(call, func() { close(sub.ready) })
+
+		go func() {
+			defer sub.Close()
+			select {
+			case <-sub.ready:
+			case <-sub.done:
+				return
+			}
+			fn(sub, call, req)
+		}()
+
+		resp.Result = sub.ID
+	}
+}
+
+// unsubscribe ends the subscription with the ID given in the call's params.
+func unsubscribe(resp *Response, call *Call, req *http.Request) {
+	var params []string
+	err := call.UnmarshalParams(&params)
+	if err != nil || len(params) != 1 {
+		resp.Error = &Error{
+			Code:    CodeInvalidParameters,
+			Message: "jsonrpc: expected the subscription ID as the only parameter",
+		}
+		return
+	}
+
+	peer := PeerFromRequest(req)
+	if peer == nil {
+		resp.Result = false
+		return
+	}
+
+	peer.mtx.Lock()
+	sub, ok := peer.subs[params[0]]
+	peer.mtx.Unlock()
+
+	if ok {
+		sub.Close()
+	}
+	resp.Result = ok
+}
+
+// RegisterSubscription registers a method that starts a subscription when
+// called over a persistent connection, and the UnsubscribeMethod used to end
+// it.
+func (dispatcher *MapDispatcher) RegisterSubscription(name string, fn SubscriptionFunc) error {
+	err := dispatcher.Register(name, subscribe(fn))
+	if err != nil {
+		return err
+	}
+
+	dispatcher.mtx.Lock()
+	defer dispatcher.mtx.Unlock()
+
+	if _, ok := dispatcher.methods[UnsubscribeMethod]; !ok {
+		dispatcher.methods[UnsubscribeMethod] = unsubscribe
+	}
+	return nil
+}
+
+// RegisterSubscription adds the subscription to the DefaultDispatcher
+func RegisterSubscription(name string, fn SubscriptionFunc) error {
+	return DefaultDispatcher.RegisterSubscription(name, fn)
+}
+
+// ClientSubscription is the client side of a subscription. Events are
+// decoded into the channel given to Subscribe, which is closed when the
+// subscription ends.
+//
+// Events wait in a queue of up to Peer.MaxQueuedEvents until the channel is
+// read.
+// The subscription ends with ErrSubscriptionOverflow when the queue is full,
+// or with the decoding error when an event does not fit the channel.
+type ClientSubscription struct {
+	ID      string
+	peer    *Peer
+	channel reflect.Value
+	queue   []json.RawMessage
+	max     int
+	wake    chan struct{}
+	done    chan struct{}
+	err     error
+	mtx     *sync.Mutex
+	once    *sync.Once
+}
+
+// forward decodes queued events and sends them on the channel until the
+// subscription ends.
+func (sub *ClientSubscription) forward() {
+	defer sub.channel.Close()
+
+	elem := sub.channel.Type().Elem()
+	for {
+		sub.mtx.Lock()
+		queue := sub.queue
+		sub.queue = nil
+		sub.mtx.Unlock()
+
+		for _, data := range queue {
+			event := reflect.New(elem)
+			err := DefaultJSONEngine.Unmarshal(data, event.Interface())
+			if err != nil {
+				sub.fail(fmt.Errorf("jsonrpc: unable to decode subscription event: %s", err))
+				return
+			}
+
+			chosen, _, _ := reflect.Select([]reflect.SelectCase{
+				{Dir: reflect.SelectSend, Chan: sub.channel, Send: event.Elem()},
+				{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(sub.done)},
+			})
+			if chosen == 1 {
+				return
+			}
+		}
+
+		select {
+		case <-sub.wake:
+		case <-sub.done:
+			return
+		}
+	}
+}
+
+func (sub *ClientSubscription) push(data json.RawMessage) {
+	sub.mtx.Lock()
+	if len(sub.queue) >= sub.max {
+		sub.mtx.Unlock()
+		sub.fail(ErrSubscriptionOverflow)
+		return
+	}
+	sub.queue = append(sub.queue, data)
+	sub.mtx.Unlock()
+
+	select {
+	case sub.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (sub *ClientSubscription) end(err error) {
+	sub.once.Do(func() {
+		sub.mtx.Lock()
+		sub.err = err
+		sub.mtx.Unlock()
+		close(sub.done)
+	})
+}
+
+// fail ends the subscription with the error and tells the server to stop
+// sending events. The server is notified from a goroutine of its own as fail
+// may be called while the connection is being read.
+func (sub *ClientSubscription) fail(err error) {
+	sub.peer.mtx.Lock()
+	delete(sub.peer.clientSubs, sub.ID)
+	sub.peer.mtx.Unlock()
+
+	sub.end(err)
+	go sub.peer.Notify(UnsubscribeMethod, []string{sub.ID})
+}
+
+// Err returns the reason the subscription ended, or nil if it is still
+// active or was ended with Unsubscribe.
+func (sub *ClientSubscription) Err() error {
+	sub.mtx.Lock()
+	defer sub.mtx.Unlock()
+	return sub.err
+}
+
+// Done returns a channel that is closed when the subscription ends.
+func (sub *ClientSubscription) Done() <-chan struct{} {
+	return sub.done
+}
+
+// Unsubscribe ends the subscription and tells the server to stop sending
+// events.
+func (sub *ClientSubscription) Unsubscribe() error {
+	sub.peer.mtx.Lock()
+	delete(sub.peer.clientSubs, sub.ID)
+	sub.peer.mtx.Unlock()
+
+	sub.end(nil)
+
+	var ok bool
+	return sub.peer.Call(UnsubscribeMethod, []string{sub.ID}, &ok)
+}
+
+// deliverEvent routes a subscription event to its ClientSubscription,
+// reporting whether the message was one.
+func (peer *Peer) deliverEvent(msg *message) bool {
+	if msg.Method != SubscriptionMethod || msg.ID != nil {
+		return false
+	}
+
+	var event subscriptionEvent
+	err := DefaultJSONEngine.Unmarshal(msg.Params, &event)
+	if err != nil {
+		return false
+	}
+
+	peer.mtx.Lock()
+	sub, ok := peer.clientSubs[event.Subscription]
+	peer.mtx.Unlock()
+
+	if ok {
+		sub.push(event.Result)
+	}
+	return ok
+}
+
+// Subscribe calls a subscription method on the other side of the connection
+// and sends the events it produces, decoded, to the channel. channel must be
+// a writable channel, and is closed when the subscription ends.
+func (peer *Peer) Subscribe(method string, params interface{}, channel interface{}) (*ClientSubscription, error) {
+	ch := reflect.ValueOf(channel)
+	if ch.Kind() != reflect.Chan || ch.Type().ChanDir()&reflect.SendDir == 0 {
+		return nil, fmt.Errorf("jsonrpc: subscription channel must be a writable channel, not %T", channel)
+	}
+
+	max := peer.MaxQueuedEvents
+	if max <= 0 {
+		max = defaultMaxQueuedEvents
+	}
+
+	sub := &ClientSubscription{
+		peer:    peer,
+		channel: ch,
+		max:     max,
+		wake:    make(chan struct{}, 1),
+		done:    make(chan struct{}),
+		mtx:     new(sync.Mutex),
+		once:    new(sync.Once),
+	}
+
+	// the subscription is registered as soon as the response is read so no
+	// events following it are missed
+	resp, err := peer.call(method, params, func(resp *clientResponse) {
+		if resp.Error != nil || DefaultJSONEngine.Unmarshal(resp.Result, &sub.ID) != nil {
+			return
+		}
+		peer.mtx.Lock()
+		defer peer.mtx.Unlock()
+		if !peer.alive() {
+			sub.end(peer.err)
+			return
+		}
+		peer.clientSubs[sub.ID] = sub
+	})
+	if err != nil {
+		return nil, err
+	}
+	if resp.Error != nil {
+		return nil, resp.Error
+	}
+	if sub.ID == "" {
+		return nil, fmt.Errorf("jsonrpc: %s did not respond with a subscription ID", method)
+	}
+
+	go sub.forward()
+	return sub, nil
+}
@@ -0,0 +1,242 @@
+package jsonrpc
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type priceEvent struct {
+	Venue string `json:"venue"`
+	Price int    `json:"price"`
+}
+
+func priceSubscription(stopped chan struct{}) SubscriptionFunc {
+	return func(sub *Subscription, call *Call, req *http.Request) {
+		var venue string
+		call.UnmarshalParams(&venue)
+		for i := 1; ; i++ {
+			err := sub.Notify(&priceEvent{Venue: venue, Price: i})
+			if err != nil {
+				break
+			}
+			select {
+			case <-sub.Done():
+				close(stopped)
+				return
+			case <-time.After(time.Millisecond):
+			}
+		}
+		close(stopped)
+	}
+}
+
+func newPriceDispatcher(stopped chan struct{}) *MapDispatcher {
+	dispatcher := NewMapDispatcher()
+	dispatcher.RegisterSubscription("prices", priceSubscription(stopped))
+	return dispatcher
+}
+
+func TestPeer_Subscribe(t *testing.T) {
+	serverConn, clientConn := newPipeConns()
+	stopped := make(chan struct{})
+
+	server := newPeer(serverConn, newPriceDispatcher(stopped), newConnRequest(nil))
+	go server.serve()
+
+	client := newPeer(clientConn, nil, newConnRequest(nil))
+	go client.serve()
+	defer client.Close()
+
+	events := make(chan priceEvent)
+	sub, err := client.Subscribe("prices", "palladium", events)
+	if !assert.Nil(t, err) {
+		t.FailNow()
+	}
+
+	for i := 1; i <= 3; i++ {
+		event := <-events
+		assert.Equal(t, "palladium", event.Venue)
+		assert.Equal(t, i, event.Price)
+	}
+
+	assert.Nil(t, sub.Unsubscribe())
+	<-stopped
+
+	for range events {
+	}
+	assert.Nil(t, sub.Err())
+}
+
+func TestPeer_Subscribe_group(t *testing.T) {
+	tests := []struct {
+		name     string
+		dispatch func(stopped chan struct{}) Dispatcher
+	}{
+		{"group", func(stopped chan struct{}) Dispatcher {
+			dispatcher := NewMapDispatcher()
+			dispatcher.Group("market").RegisterSubscription("prices", priceSubscription(stopped))
+			return dispatcher
+		}},
+		{"mount", func(stopped chan struct{}) Dispatcher {
+			dispatcher := NewMapDispatcher()
+			dispatcher.Mount("market", newPriceDispatcher(stopped))
+			return dispatcher
+		}},
+	}
+
+	for _, test := range tests {
+		serverConn, clientConn := newPipeConns()
+		stopped := make(chan struct{})
+
+		server := newPeer(serverConn, test.dispatch(stopped), newConnRequest(nil))
+		go server.serve()
+
+		client := newPeer(clientConn, nil, newConnRequest(nil))
+		go client.serve()
+
+		events := make(chan priceEvent)
+		sub, err := client.Subscribe("market.prices", "palladium", events)
+		if !assert.Nil(t, err, test.name) {
+			t.FailNow()
+		}
+
+		// the subscription only starts once the hook for its call has run
+		event := <-events
+		assert.Equal(t, "palladium", event.Venue, test.name)
+
+		server.mtx.Lock()
+		assert.Empty(t, server.hooks, test.name)
+		server.mtx.Unlock()
+
+		assert.Nil(t, sub.Unsubscribe(), test.name)
+		<-stopped
+		client.Close()
+
+		for range events {
+		}
+		assert.Nil(t, sub.Err(), test.name)
+	}
+}
+
+func TestPeer_Subscribe_disconnect(t *testing.T) {
+	serverConn, clientConn := newPipeConns()
+	stopped := make(chan struct{})
+
+	server := newPeer(serverConn, newPriceDispatcher(stopped), newConnRequest(nil))
+	go server.serve()
+
+	client := newPeer(clientConn, nil, newConnRequest(nil))
+	go client.serve()
+
+	events := make(chan priceEvent)
+	sub, err := client.Subscribe("prices", "palladium", events)
+	if !assert.Nil(t, err) {
+		t.FailNow()
+	}
+	<-events
+
+	client.Close()
+	<-stopped
+
+	for range events {
+	}
+	assert.Equal(t, ErrClosed, sub.Err())
+}
+
+func TestPeer_Subscribe_overflow(t *testing.T) {
+	serverConn, clientConn := newPipeConns()
+	stopped := make(chan struct{})
+
+	server := newPeer(serverConn, newPriceDispatcher(stopped), newConnRequest(nil))
+	go server.serve()
+
+	client := newPeer(clientConn, nil, newConnRequest(nil))
+	client.MaxQueuedEvents = 5
+	go client.serve()
+	defer client.Close()
+
+	// the channel is not read until the subscription has ended
+	events := make(chan priceEvent)
+	sub, err := client.Subscribe("prices", "palladium", events)
+	if !assert.Nil(t, err) {
+		t.FailNow()
+	}
+
+	<-sub.Done()
+	assert.Equal(t, ErrSubscriptionOverflow, sub.Err())
+	for range events {
+	}
+
+	// the server is told to stop
+	<-stopped
+}
+
+func TestPeer_Subscribe_decode_error(t *testing.T) {
+	serverConn, clientConn := newPipeConns()
+	stopped := make(chan struct{})
+
+	server := newPeer(serverConn, newPriceDispatcher(stopped), newConnRequest(nil))
+	go server.serve()
+
+	client := newPeer(clientConn, nil, newConnRequest(nil))
+	go client.serve()
+	defer client.Close()
+
+	events := make(chan int)
+	sub, err := client.Subscribe("prices", "palladium", events)
+	if !assert.Nil(t, err) {
+		t.FailNow()
+	}
+
+	for range events {
+	}
+	if assert.NotNil(t, sub.Err()) {
+		assert.Contains(t, sub.Err().Error(), "unable to decode subscription event")
+	}
+	<-stopped
+}
+
+func TestPeer_Subscribe_bad_channel(t *testing.T) {
+	_, clientConn := newPipeConns()
+	client := newPeer(clientConn, nil, newConnRequest(nil))
+
+	_, err := client.Subscribe("prices", nil, make(<-chan int))
+	assert.NotNil(t, err)
+	_, err = client.Subscribe("prices", nil, 5)
+	assert.NotNil(t, err)
+}
+
+func TestSubscribe_over_http(t *testing.T) {
+	server := httptest.NewServer(&Handler{Dispatcher: newPriceDispatcher(make(chan struct{}))})
+	defer server.Close()
+
+	var result string
+	err := NewClient().Call(server.URL, "prices", "palladium", &result)
+	assert.Equal(t, CodeInvalidRequest, err.(*Error).Code)
+}
+
+func TestWebSocketClient_Subscribe(t *testing.T) {
+	stopped := make(chan struct{})
+	server := httptest.NewServer(&WebSocketHandler{Dispatcher: newPriceDispatcher(stopped)})
+	defer server.Close()
+
+	client := NewWebSocketClient("ws" + strings.TrimPrefix(server.URL, "http"))
+	defer client.Close()
+
+	events := make(chan *priceEvent, 10)
+	sub, err := client.Subscribe("prices", "palladium", events)
+	if !assert.Nil(t, err) {
+		t.FailNow()
+	}
+
+	event := <-events
+	assert.Equal(t, 1, event.Price)
+
+	assert.Nil(t, sub.Unsubscribe())
+	<-stopped
+}
@@ -1,35 +1,40 @@
 package jsonrpc
 
 import (
-	"bytes"
-	"encoding/json"
+	"mime"
+	"net/http"
+	"strings"
 )
 
 var (
-	// IndentOutput controls wether or not JSON output is indented.
+	// IndentOutput controls wether or not JSON output is indented. It applies
+	// to every Handler, Client and Batch without their own JSONCodec.
 	IndentOutput = true
-	// EscapeHTML controls wether json output is HTML escaped.
-	EscapeHTML = false
+	// EscapeHTML controls wether json output is HTML escaped. It applies to
+	// every Handler, Client and Batch without their own JSONCodec.
+	EscapeHTML = true
 )
 
 // Marshal is a custom json marshaller that conditionally turns off html
-// escaping and applies indentation.
+// escaping and applies indentation according to IndentOutput and EscapeHTML.
 func Marshal(v interface{}) ([]byte, error) {
+	return defaultJSONCodec().Marshal(v)
+}
 
-	buf := bytes.NewBuffer([]byte{})
-	e := json.NewEncoder(buf)
-	if IndentOutput {
-		e.SetIndent("", "  ")
-	}
-	if EscapeHTML {
-		e.SetEscapeHTML(false)
-	}
-
-	err := e.Encode(v)
-
-	if err != nil {
-		return nil, err
+// accepts reports whether the request's Accept header includes the media
+// type.
+func accepts(r *http.Request, mediatype string) bool {
+	for _, accept := range strings.Split(r.Header.Get("Accept"), ",") {
+		accepted, _, err := mime.ParseMediaType(strings.TrimSpace(accept))
+		if err == nil && accepted == mediatype {
+			return true
+		}
 	}
+	return false
+}
 
-	return buf.Bytes(), nil
+// hasContentType reports whether the response has the media type.
+func hasContentType(resp *http.Response, mediatype string) bool {
+	contentType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	return contentType == mediatype
 }
@@ -0,0 +1,11 @@
+module github.com/ingresso-group/gojsonrpc/v2/v1compat
+
+go 1.11
+
+require (
+	github.com/getsentry/raven-go v0.2.0
+	github.com/ingresso-group/gojsonrpc v0.0.7
+	github.com/ingresso-group/gojsonrpc/v2 v2.0.0
+	github.com/stretchr/testify v1.6.1
+)
+
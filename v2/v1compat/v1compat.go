@@ -0,0 +1,96 @@
+// Package v1compat serves methods written for the v1 Service with the v2
+// Handler, so servers can move to v2 without rewriting every method.
+package v1compat
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/getsentry/raven-go"
+
+	v1 "github.com/ingresso-group/gojsonrpc/v1"
+	jsonrpc "github.com/ingresso-group/gojsonrpc/v2"
+)
+
+// Method adapts a v1 method to a v2 Method.
+//
+// Calls are handled as the v1 Service handles them: params are decoded into a
+// new value from Params, which must be valid JSON, and checked with Validate,
+// failures of either being sent with the CodeInvalidParameters code. Errors
+// returned by Action are sent with the CodeInternalError code, as are panics,
+// which are reported to sentry as the v1 Service reports them.
+func Method(method v1.MethodInterface) jsonrpc.Method {
+	return func(resp *jsonrpc.Response, call *jsonrpc.Call, req *http.Request) {
+		recovered, errID := raven.CapturePanic(func() {
+			dispatch(method, resp, call, req)
+		}, map[string]string{"method": call.Method})
+
+		if recovered != nil {
+			resp.Result = nil
+			resp.Error = &jsonrpc.Error{
+				Code:    jsonrpc.CodeInternalError,
+				Message: fmt.Sprintf("jsonrpc: panic occurred calling %s", call.Method),
+				Data:    fmt.Sprint(recovered),
+			}
+			if errID != "" {
+				resp.Error.Message += " and was reported to sentry: " + errID
+			}
+		}
+	}
+}
+
+// dispatch calls the v1 method as the v1 Service calls it.
+func dispatch(method v1.MethodInterface, resp *jsonrpc.Response, call *jsonrpc.Call, req *http.Request) {
+	params := method.Params()
+
+	err := json.Unmarshal(call.Params, params)
+	if err != nil {
+		resp.Error = &jsonrpc.Error{
+			Code:    jsonrpc.CodeInvalidParameters,
+			Message: err.Error(),
+		}
+		return
+	}
+
+	err = params.Validate()
+	if err != nil {
+		resp.Error = &jsonrpc.Error{
+			Code:    jsonrpc.CodeInvalidParameters,
+			Message: err.Error(),
+		}
+		return
+	}
+
+	result, err := method.Action(req, params)
+	if err != nil {
+		resp.Error = &jsonrpc.Error{
+			Code:    jsonrpc.CodeInternalError,
+			Message: err.Error(),
+		}
+		return
+	}
+
+	resp.Result = result
+}
+
+// Register registers a v1 method with the dispatcher, the DefaultDispatcher
+// when nil.
+func Register(dispatcher *jsonrpc.MapDispatcher, name string, method v1.MethodInterface) error {
+	if dispatcher == nil {
+		dispatcher = jsonrpc.DefaultDispatcher
+	}
+	return dispatcher.Register(name, Method(method))
+}
+
+// RegisterMethods registers each of the v1 methods by name with the
+// dispatcher, the DefaultDispatcher when nil, stopping at the first error.
+func RegisterMethods(dispatcher *jsonrpc.MapDispatcher, methods map[string]v1.MethodInterface) error {
+	for name, method := range methods {
+		err := Register(dispatcher, name, method)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,102 @@
+package v1compat
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	v1 "github.com/ingresso-group/gojsonrpc/v1"
+	jsonrpc "github.com/ingresso-group/gojsonrpc/v2"
+)
+
+type greetParams struct {
+	Name string `json:"name"`
+}
+
+func (params *greetParams) Validate() error {
+	if params.Name == "" {
+		return errors.New("name is required")
+	}
+	return nil
+}
+
+type greetMethod struct{}
+
+func (method *greetMethod) Params() v1.ParametersInterface {
+	return new(greetParams)
+}
+
+func (method *greetMethod) Action(r *http.Request, p v1.ParametersInterface) (interface{}, error) {
+	params := p.(*greetParams)
+	if params.Name == "trouble" {
+		panic("trouble is not expected")
+	}
+	if params.Name == "nobody" {
+		return nil, fmt.Errorf("%s is not welcome", params.Name)
+	}
+	return fmt.Sprintf("hello %s from %s", params.Name, r.Header.Get("X-Venue")), nil
+}
+
+func newServer(t *testing.T) *httptest.Server {
+	dispatcher := jsonrpc.NewMapDispatcher()
+	err := RegisterMethods(dispatcher, map[string]v1.MethodInterface{
+		"greet": &greetMethod{},
+	})
+	assert.Nil(t, err)
+	assert.NotNil(t, Register(dispatcher, "greet", &greetMethod{}))
+
+	return httptest.NewServer(&jsonrpc.Handler{Dispatcher: dispatcher})
+}
+
+func call(t *testing.T, url string, params interface{}) (string, error) {
+	client := jsonrpc.NewClient()
+	req, err := jsonrpc.NewRequest(url, "greet", params)
+	if !assert.Nil(t, err) {
+		t.FailNow()
+	}
+	req.Header.Set("X-Venue", "the Lyceum")
+
+	var result string
+	err = client.Do(req, &result)
+	return result, err
+}
+
+func TestMethod(t *testing.T) {
+	server := newServer(t)
+	defer server.Close()
+
+	result, err := call(t, server.URL, map[string]string{"name": "sam"})
+	assert.Nil(t, err)
+	assert.Equal(t, "hello sam from the Lyceum", result)
+}
+
+func TestMethod_errors(t *testing.T) {
+	server := newServer(t)
+	defer server.Close()
+
+	// invalid params
+	_, err := call(t, server.URL, map[string]int{"name": 1})
+	assert.Equal(t, jsonrpc.CodeInvalidParameters, err.(*jsonrpc.Error).Code)
+
+	// null params leave the params empty, so they fail Validate
+	_, err = call(t, server.URL, nil)
+	assert.Equal(t, jsonrpc.CodeInvalidParameters, err.(*jsonrpc.Error).Code)
+
+	// params failing Validate
+	_, err = call(t, server.URL, map[string]string{"name": ""})
+	assert.Equal(t, &jsonrpc.Error{Code: jsonrpc.CodeInvalidParameters, Message: "name is required"}, err)
+
+	// errors from Action
+	_, err = call(t, server.URL, map[string]string{"name": "nobody"})
+	assert.Equal(t, &jsonrpc.Error{Code: jsonrpc.CodeInternalError, Message: "nobody is not welcome"}, err)
+
+	// panics in Action
+	_, err = call(t, server.URL, map[string]string{"name": "trouble"})
+	assert.Equal(t, jsonrpc.CodeInternalError, err.(*jsonrpc.Error).Code)
+	assert.Contains(t, err.Error(), "panic occurred calling greet")
+	assert.Equal(t, "trouble is not expected", err.(*jsonrpc.Error).Data)
+}
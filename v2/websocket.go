@@ -0,0 +1,255 @@
+package jsonrpc
+
+import (
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+const (
+	// DefaultPingInterval is how often a WebSocketClient pings the server.
+	DefaultPingInterval = 30 * time.Second
+	// DefaultPongWait is how long a WebSocketClient waits to hear from the
+	// server before considering the connection dead.
+	DefaultPongWait = 60 * time.Second
+)
+
+// wsConn adapts a websocket.Conn to a messageConn.
+type wsConn struct {
+	conn *websocket.Conn
+	mtx  *sync.Mutex
+}
+
+// newWSConn returns a wsConn reading messages of up to maxSize bytes, or
+// defaultMaxMessageSize when zero.
+func newWSConn(conn *websocket.Conn, maxSize int) *wsConn {
+	if maxSize <= 0 {
+		maxSize = defaultMaxMessageSize
+	}
+	conn.SetReadLimit(int64(maxSize))
+
+	return &wsConn{
+		conn: conn,
+		mtx:  new(sync.Mutex),
+	}
+}
+
+func (conn *wsConn) ReadMessage() ([]byte, error) {
+	_, data, err := conn.conn.ReadMessage()
+	if err == websocket.ErrReadLimit {
+		return nil, ErrMessageTooLarge
+	}
+	return data, err
+}
+
+func (conn *wsConn) WriteMessage(data []byte) error {
+	conn.mtx.Lock()
+	defer conn.mtx.Unlock()
+	return conn.conn.WriteMessage(websocket.TextMessage, data)
+}
+
+func (conn *wsConn) Close() error {
+	return conn.conn.Close()
+}
+
+// WebSocketHandler upgrades http requests to WebSocket connections and serves
+// JSONRPC calls received on them with the Dispatcher.
+//
+// Every message received on the connection may be a single call or a batch,
+// and is answered with a single message. The http.Request passed to the
+// Dispatcher is the original upgrade request, from which methods can get the
+// Peer for the connection with PeerFromRequest to call back to the client.
+//
+// A connection sending a message larger than MaxMessageSize bytes, 10MB when
+// zero, is closed.
+type WebSocketHandler struct {
+	Dispatcher     Dispatcher
+	Upgrader       *websocket.Upgrader
+	MaxMessageSize int
+}
+
+// ServeHTTP upgrades the connection and serves calls on it until it is
+// closed. Implements the http.Handler interface
+func (handler *WebSocketHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	upgrader := handler.Upgrader
+	if upgrader == nil {
+		upgrader = &websocket.Upgrader{}
+	}
+
+	// the upgrader writes its own http error response on failure
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+
+	peer := newPeer(newWSConn(conn, handler.MaxMessageSize), handler.Dispatcher, r)
+	defer peer.Close()
+
+	peer.serve()
+}
+
+// WebSocketClient makes JSONRPC calls over a single persistent WebSocket
+// connection. Calls may be made concurrently from many goroutines and are
+// matched to their responses by ID.
+//
+// The connection is kept alive with pings. When it is lost, calls waiting on
+// it fail and the next call dials a new connection.
+//
+// Calls made by the server to the client are served with the Dispatcher.
+// The connection is closed when the server sends a message larger than
+// MaxMessageSize bytes, 10MB when zero.
+type WebSocketClient struct {
+	URL            string
+	Header         http.Header
+	Dialer         *websocket.Dialer
+	Dispatcher     Dispatcher
+	PingInterval   time.Duration
+	PongWait       time.Duration
+	MaxMessageSize int
+	conn           *Peer
+	closed         bool
+	mtx            *sync.Mutex
+}
+
+// NewWebSocketClient returns a pointer to a WebSocketClient for the given
+// URL. The connection is dialed when the first call is made.
+func NewWebSocketClient(url string) *WebSocketClient {
+	client := &WebSocketClient{
+		URL:          url,
+		Dialer:       websocket.DefaultDialer,
+		PingInterval: DefaultPingInterval,
+		PongWait:     DefaultPongWait,
+		mtx:          new(sync.Mutex),
+	}
+	return client
+}
+
+// DialWebSocket returns a WebSocketClient connected to the given URL.
+func DialWebSocket(url string, header http.Header) (*WebSocketClient, error) {
+	client := NewWebSocketClient(url)
+	client.Header = header
+
+	_, err := client.connect()
+	if err != nil {
+		return nil, err
+	}
+	return client, nil
+}
+
+// connect returns the current connection, dialing a new one when there is
+// none or the last one was lost.
+func (client *WebSocketClient) connect() (*Peer, error) {
+	client.mtx.Lock()
+	defer client.mtx.Unlock()
+
+	if client.closed {
+		return nil, ErrClosed
+	}
+
+	if client.conn != nil && client.conn.alive() {
+		return client.conn, nil
+	}
+
+	dialer := client.Dialer
+	if dialer == nil {
+		dialer = websocket.DefaultDialer
+	}
+
+	conn, resp, err := dialer.Dial(client.URL, client.Header)
+	if err != nil {
+		return nil, err
+	}
+
+	if client.PongWait > 0 {
+		conn.SetReadDeadline(time.Now().Add(client.PongWait))
+		conn.SetPongHandler(func(string) error {
+			conn.SetReadDeadline(time.Now().Add(client.PongWait))
+			return nil
+		})
+	}
+
+	req := resp.Request
+	if req == nil {
+		req = newConnRequest(nil)
+	}
+
+	client.conn = newPeer(newWSConn(conn, client.MaxMessageSize), client.Dispatcher, req)
+	go client.conn.serve()
+
+	if client.PingInterval > 0 {
+		go client.keepAlive(conn, client.conn)
+	}
+
+	return client.conn, nil
+}
+
+// keepAlive pings the server until the connection is lost.
+func (client *WebSocketClient) keepAlive(conn *websocket.Conn, peer *Peer) {
+	ticker := time.NewTicker(client.PingInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			deadline := time.Now().Add(client.PingInterval)
+			err := conn.WriteControl(websocket.PingMessage, nil, deadline)
+			if err != nil {
+				peer.Close()
+				return
+			}
+		case <-peer.done:
+			conn.Close()
+			return
+		}
+	}
+}
+
+// Call makes a single JSONRPC call over the connection and deserialises the
+// result into the result argument.
+func (client *WebSocketClient) Call(method string, params interface{}, result interface{}) error {
+	conn, err := client.connect()
+	if err != nil {
+		return err
+	}
+	return conn.Call(method, params, result)
+}
+
+// Notify sends a call to the server that expects no response.
+func (client *WebSocketClient) Notify(method string, params interface{}) error {
+	conn, err := client.connect()
+	if err != nil {
+		return err
+	}
+	return conn.Notify(method, params)
+}
+
+// Subscribe calls a subscription method on the server and sends the events
+// it produces to the channel. The subscription ends if the connection is
+// lost.
+func (client *WebSocketClient) Subscribe(method string, params interface{}, channel interface{}) (*ClientSubscription, error) {
+	conn, err := client.connect()
+	if err != nil {
+		return nil, err
+	}
+	return conn.Subscribe(method, params, channel)
+}
+
+// Peer returns the Peer for the current connection, dialing one if needed.
+func (client *WebSocketClient) Peer() (*Peer, error) {
+	return client.connect()
+}
+
+// Close closes the connection. Calls waiting for a response will fail and
+// further calls will return ErrClosed.
+func (client *WebSocketClient) Close() error {
+	client.mtx.Lock()
+	defer client.mtx.Unlock()
+
+	client.closed = true
+	if client.conn == nil {
+		return nil
+	}
+	return client.conn.Close()
+}
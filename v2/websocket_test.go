@@ -0,0 +1,126 @@
+package jsonrpc
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWebSocketClient_Call(t *testing.T) {
+	server := httptest.NewServer(&WebSocketHandler{Dispatcher: newAddDispatcher()})
+	defer server.Close()
+
+	url := "ws" + strings.TrimPrefix(server.URL, "http")
+	client, err := DialWebSocket(url, nil)
+	if !assert.Nil(t, err) {
+		t.FailNow()
+	}
+	defer client.Close()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			var result int
+			err := client.Call("add", []int{i, 1}, &result)
+			assert.Nil(t, err)
+			assert.Equal(t, i+1, result)
+		}(i)
+	}
+	wg.Wait()
+
+	assert.Nil(t, client.Notify("add", []int{1}))
+}
+
+func TestWebSocketClient_reconnect(t *testing.T) {
+	server := httptest.NewServer(&WebSocketHandler{Dispatcher: newAddDispatcher()})
+	defer server.Close()
+
+	url := "ws" + strings.TrimPrefix(server.URL, "http")
+	client := NewWebSocketClient(url)
+	defer client.Close()
+
+	var result int
+	err := client.Call("add", []int{1, 2}, &result)
+	assert.Nil(t, err)
+
+	client.mtx.Lock()
+	lost := client.conn
+	client.mtx.Unlock()
+	lost.conn.Close()
+	<-lost.done
+
+	err = client.Call("add", []int{3, 4}, &result)
+	assert.Nil(t, err)
+	assert.Equal(t, 7, result)
+}
+
+func TestWebSocketClient_closed(t *testing.T) {
+	client := NewWebSocketClient("ws://localhost:0")
+	client.Close()
+
+	var result int
+	err := client.Call("add", []int{1, 2}, &result)
+	assert.Equal(t, ErrClosed, err)
+}
+
+func TestWebSocketClient_server_calls(t *testing.T) {
+	dispatcher := NewMapDispatcher()
+	dispatcher.Register("greet", func(resp *Response, call *Call, req *http.Request) {
+		var name string
+		err := PeerFromRequest(req).Call("name", nil, &name)
+		if err != nil {
+			resp.Error = &Error{Code: CodeInternalError, Message: err.Error()}
+			return
+		}
+		resp.Result = "hello " + name
+	})
+	server := httptest.NewServer(&WebSocketHandler{Dispatcher: dispatcher})
+	defer server.Close()
+
+	clientDispatcher := NewMapDispatcher()
+	clientDispatcher.Register("name", func(resp *Response, call *Call, req *http.Request) {
+		resp.Result = "world"
+	})
+
+	client := NewWebSocketClient("ws" + strings.TrimPrefix(server.URL, "http"))
+	client.Dispatcher = clientDispatcher
+	defer client.Close()
+
+	var result string
+	err := client.Call("greet", nil, &result)
+	assert.Nil(t, err)
+	assert.Equal(t, "hello world", result)
+}
+
+func TestWebSocket_MaxMessageSize(t *testing.T) {
+	server := httptest.NewServer(&WebSocketHandler{Dispatcher: newAddDispatcher(), MaxMessageSize: 64})
+	defer server.Close()
+	url := "ws" + strings.TrimPrefix(server.URL, "http")
+
+	client := NewWebSocketClient(url)
+	defer client.Close()
+
+	var result int
+	assert.Nil(t, client.Call("add", []int{1, 2}, &result))
+	assert.Equal(t, 3, result)
+
+	// the server closes the connection on a message over its limit
+	numbers := make([]int, 100)
+	assert.NotNil(t, client.Call("add", numbers, &result))
+
+	// and the client on a response over its own
+	client = NewWebSocketClient(url)
+	client.MaxMessageSize = 16
+	defer client.Close()
+
+	err := client.Call("add", []int{1, 2}, &result)
+	if assert.NotNil(t, err) {
+		assert.Contains(t, err.Error(), ErrMessageTooLarge.Error())
+	}
+}